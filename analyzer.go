@@ -0,0 +1,118 @@
+package imagecolor
+
+import (
+	"image"
+	"math"
+	"sort"
+
+	"gonum.org/v1/gonum/stat"
+)
+
+// Analyzer - Computes ImageColors and ProminentColors with reusable buffers.
+// Buffers grow to the largest image seen, so repeated calls allocate almost nothing.
+// An Analyzer is not safe for concurrent use; use one per goroutine or a sync.Pool.
+type Analyzer struct {
+	// Palette classifies pixels, nil uses every Material color series like closestMaterialColor.
+	Palette *Palette
+
+	ic     ImageColors
+	pix    []ColorHSL
+	values []float64
+	counts [materialBlack + 1]int
+}
+
+// NewAnalyzer - Create an Analyzer. The zero value is also ready to use.
+func NewAnalyzer() *Analyzer {
+	return &Analyzer{}
+}
+
+// ImageColors - ImageColors of an image, same as GetImageColors.
+// The result uses the Analyzer's buffers and is only valid until the next call.
+func (a *Analyzer) ImageColors(m image.Image) ImageColors {
+	bounds := m.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if cap(a.pix) < width*height {
+		a.pix = make([]ColorHSL, width*height)
+	}
+	if cap(a.ic) < width {
+		a.ic = make(ImageColors, width)
+	}
+	a.ic = a.ic[:width]
+	for x := 0; x < width; x++ {
+		a.ic[x] = a.pix[x*height : (x+1)*height : (x+1)*height]
+	}
+	fillHSL(a.ic, m)
+	return a.ic
+}
+
+// ProminentColors - Prominent Colors of an image, identical to
+// GetImageColors(m).ProminentColors(limit) when Palette is nil.
+// (limit) percentage limit of promiment colors to return
+func (a *Analyzer) ProminentColors(m image.Image, limit float64) ProminentColors {
+	return a.ProminentColorsFrom(a.ImageColors(m), limit)
+}
+
+// ClassMap - ColorClassMap of ImageColors classified with the Analyzer's Palette,
+// consistent with ProminentColorsFrom
+func (a *Analyzer) ClassMap(ic ImageColors) *ColorClassMap {
+	return ic.ClassMapPalette(a.Palette)
+}
+
+// ProminentColorsFrom - Prominent Colors of ImageColors, such as the result of ImageColors.
+// (limit) percentage limit of promiment colors to return
+func (a *Analyzer) ProminentColorsFrom(ic ImageColors, limit float64) ProminentColors {
+	var pc ProminentColors
+	palette := a.Palette
+	if palette == nil {
+		palette = materialPalette
+	}
+
+	a.counts = [materialBlack + 1]int{}
+	total := 0
+	for _, col := range ic {
+		for _, c := range col {
+			a.counts[palette.Classify(c)]++
+			total++
+		}
+	}
+	n := 0
+	for _, num := range a.counts {
+		if float64(num)/float64(total) > limit {
+			n++
+		}
+	}
+	if n > 0 {
+		pc.Colors = make([]ProminentColor, 0, n)
+	}
+	for mc := materialRed; mc <= materialBlack; mc++ {
+		if w := float64(a.counts[mc]) / float64(total); w > limit {
+			pc.Colors = append(pc.Colors, ProminentColor{Color: mc, W: w})
+		}
+	}
+	sort.Sort(pc)
+
+	mean, std := stat.MeanStdDev(a.channel(ic, saturationValue), nil)
+	pc.Saturation = [2]float64{mean, std}
+	light := a.channel(ic, lightValue)
+	mean, std = stat.MeanStdDev(light, nil)
+	pc.Lightness = [2]float64{mean, std}
+	pc.Colorfulness = math.Sqrt(pc.Saturation[0] + pc.Saturation[1])
+
+	if len(light) > 0 {
+		sort.Float64s(light)
+		pc.Qlightness = stat.Quantile(0.70, stat.Empirical, light, nil)
+	}
+	return pc
+}
+
+// channel - Values of a ColorHSL channel in the order of ImageColors.getValues,
+// stored in the Analyzer's buffer
+func (a *Analyzer) channel(ic ImageColors, value uint8) []float64 {
+	a.values = a.values[:0]
+	for _, col := range ic {
+		for _, c := range col {
+			a.values = append(a.values, c[value])
+		}
+	}
+	return a.values
+}
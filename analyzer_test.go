@@ -0,0 +1,49 @@
+package imagecolor
+
+import (
+	"image"
+	"image/color"
+	"reflect"
+	"testing"
+)
+
+func analyzerTestImage() image.Image {
+	m := image.NewNRGBA(image.Rect(0, 0, 40, 30))
+	for x := 0; x < 40; x++ {
+		for y := 0; y < 30; y++ {
+			switch {
+			case x < 20:
+				m.Set(x, y, color.NRGBA{220, 40, 40, 255})
+			case y < 10:
+				m.Set(x, y, color.NRGBA{30, 90, uint8(200 + x), 255})
+			default:
+				m.Set(x, y, color.NRGBA{uint8(x * 6), uint8(y * 8), 60, 255})
+			}
+		}
+	}
+	return m
+}
+
+func TestAnalyzerProminentColors(t *testing.T) {
+	m := analyzerTestImage()
+	want := GetImageColors(m).ProminentColors(0.01)
+	a := NewAnalyzer()
+	// Analyze a larger image first so that buffers are reused
+	a.ProminentColors(image.NewRGBA(image.Rect(0, 0, 64, 64)), 0.01)
+	got := a.ProminentColors(m, 0.01)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ProminentColors was incorrect, got: %v, want: %v.", got, want)
+	}
+}
+
+func TestAnalyzerAllocations(t *testing.T) {
+	m := analyzerTestImage()
+	a := NewAnalyzer()
+	a.ProminentColors(m, 0.01)
+	allocs := testing.AllocsPerRun(10, func() {
+		a.ProminentColors(m, 0.01)
+	})
+	if allocs > 5 {
+		t.Errorf("Allocations were incorrect, got: %v, want: <= %v.", allocs, 5)
+	}
+}
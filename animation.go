@@ -0,0 +1,255 @@
+package imagecolor
+
+import (
+	"errors"
+	"image"
+	"image/draw"
+	"image/gif"
+	"io"
+	"math"
+	"sort"
+	"time"
+)
+
+// Errors
+const (
+	ErrorNoFrames = "Animation has no frames"
+)
+
+// defaultFrameDelay - Delay used for frames without a delay, as browsers do
+const defaultFrameDelay = 100 * time.Millisecond
+
+// Frame - Single composited frame of an animation
+type Frame struct {
+	Image image.Image
+	Delay time.Duration
+}
+
+// FrameIterator - Iterates over the frames of an animation.
+// Next returns io.EOF after the last frame.
+type FrameIterator interface {
+	Next() (Frame, error)
+}
+
+// gifFrames - FrameIterator over a *gif.GIF
+type gifFrames struct {
+	g        *gif.GIF
+	i        int
+	canvas   *image.RGBA
+	previous *image.RGBA
+}
+
+// NewGIFFrames - FrameIterator over the frames of a GIF.
+// Frames are composited on the logical screen using each frame's disposal method,
+// so every Frame is the full image as it is displayed.
+func NewGIFFrames(g *gif.GIF) FrameIterator {
+	bounds := image.Rect(0, 0, g.Config.Width, g.Config.Height)
+	for _, p := range g.Image {
+		bounds = bounds.Union(p.Bounds())
+	}
+	return &gifFrames{g: g, canvas: image.NewRGBA(bounds)}
+}
+
+// Next - Next composited frame
+func (gf *gifFrames) Next() (Frame, error) {
+	if gf.i >= len(gf.g.Image) {
+		return Frame{}, io.EOF
+	}
+	i := gf.i
+	gf.i++
+	src := gf.g.Image[i]
+
+	// Dispose of the previous frame
+	if i > 0 {
+		prev := gf.g.Image[i-1]
+		switch gifDisposal(gf.g, i-1) {
+		case gif.DisposalBackground:
+			draw.Draw(gf.canvas, prev.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			if gf.previous != nil {
+				draw.Draw(gf.canvas, prev.Bounds(), gf.previous, prev.Bounds().Min, draw.Src)
+			}
+		}
+	}
+	if gifDisposal(gf.g, i) == gif.DisposalPrevious {
+		if gf.previous == nil {
+			gf.previous = image.NewRGBA(gf.canvas.Bounds())
+		}
+		copy(gf.previous.Pix, gf.canvas.Pix)
+	}
+	draw.Draw(gf.canvas, src.Bounds(), src, src.Bounds().Min, draw.Over)
+
+	frame := image.NewRGBA(gf.canvas.Bounds())
+	copy(frame.Pix, gf.canvas.Pix)
+	delay := defaultFrameDelay
+	if i < len(gf.g.Delay) && gf.g.Delay[i] > 0 {
+		delay = time.Duration(gf.g.Delay[i]) * 10 * time.Millisecond
+	}
+	return Frame{Image: frame, Delay: delay}, nil
+}
+
+func gifDisposal(g *gif.GIF, i int) byte {
+	if i < len(g.Disposal) {
+		return g.Disposal[i]
+	}
+	return gif.DisposalNone
+}
+
+// AnimationOptions - Options for AnalyzeFrames
+type AnimationOptions struct {
+	// Limit is the percentage limit of prominent colors to return.
+	Limit float64
+	// Size downscales frames so that their largest side is at most Size pixels (0 keeps the frame size).
+	Size int
+}
+
+// FrameColors - Color analysis of a single frame
+type FrameColors struct {
+	Delay  time.Duration
+	Colors ProminentColors
+	// Change is the palette change from the previous frame in the range [0, 1]
+	// (total variation distance between the color distributions).
+	Change float64
+}
+
+// AnimationColors - Color analysis of an animation
+type AnimationColors struct {
+	Frames   []FrameColors
+	Duration time.Duration
+	// Palette is the duration weighted palette of the whole animation.
+	Palette []ProminentColor
+}
+
+// AnalyzeFrames - Prominent colors of every frame, a duration weighted palette
+// and frame to frame palette changes.
+// Pixels are weighted by their alpha, so transparent areas are ignored, and fully
+// transparent frames have no colors. Frames without a Delay last defaultFrameDelay.
+func AnalyzeFrames(it FrameIterator, opts AnimationOptions) (*AnimationColors, error) {
+	res := &AnimationColors{}
+	total := make(map[MaterialColor]float64)
+	var previous map[MaterialColor]float64
+	var seconds float64 // duration of the frames with visible pixels
+
+	for {
+		frame, err := it.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if frame.Delay <= 0 {
+			frame.Delay = defaultFrameDelay
+		}
+		pc, visible := frameColors(frame.Image, opts.Size)
+		if visible {
+			seconds += frame.Delay.Seconds()
+		}
+		weights := make(map[MaterialColor]float64, len(pc.Colors))
+		for _, c := range pc.Colors {
+			weights[c.Color] = c.W
+			total[c.Color] += c.W * frame.Delay.Seconds()
+		}
+		fc := FrameColors{Delay: frame.Delay, Colors: pc}
+		fc.Colors.Colors = limitColors(pc.Colors, opts.Limit)
+		if previous != nil {
+			fc.Change = paletteChange(previous, weights)
+		}
+		previous = weights
+		res.Frames = append(res.Frames, fc)
+		res.Duration += frame.Delay
+	}
+	if len(res.Frames) == 0 {
+		return nil, errors.New(ErrorNoFrames)
+	}
+
+	for mc, w := range total {
+		if w/seconds > opts.Limit {
+			res.Palette = append(res.Palette, ProminentColor{Color: mc, W: w / seconds})
+		}
+	}
+	sort.Sort(ProminentColors{Colors: res.Palette})
+	return res, nil
+}
+
+// frameColors - Prominent colors of a frame downscaled to size (0 keeps the frame size),
+// with every pixel weighted by its alpha. visible is false for a fully transparent frame.
+func frameColors(m image.Image, size int) (pc ProminentColors, visible bool) {
+	b := m.Bounds()
+	width, height := b.Dx(), b.Dy()
+	if size > 0 && maxInt(width, height) > size {
+		scale := float64(size) / float64(maxInt(width, height))
+		width, height = maxInt(int(float64(width)*scale), 1), maxInt(int(float64(height)*scale), 1)
+	}
+	if o, ok := m.(interface{ Opaque() bool }); ok && o.Opaque() {
+		if width != b.Dx() || height != b.Dy() {
+			m = Downscale(m, width, height, GammaAveraging)
+		}
+		return GetImageColors(m).ProminentColors(0), true
+	}
+
+	// Pixels are premultiplied by alpha, so dividing the mean color of a downscaled
+	// pixel by its mean alpha gives the alpha weighted mean color.
+	alpha := image.NewAlpha(b)
+	draw.Draw(alpha, b, m, b.Min, draw.Src)
+	img := Downscale(m, width, height, GammaAveraging)
+	am := Downscale(alpha, width, height, GammaAveraging)
+	sm := newSaliencyMap(width, height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			i := img.PixOffset(x, y)
+			a := float64(am.Pix[i]) / 0xff
+			if a == 0 {
+				continue
+			}
+			visible = true
+			sm[x][y] = a
+			for c := i; c < i+3; c++ {
+				img.Pix[c] = uint8(math.Min(math.Round(float64(img.Pix[c])/a), 0xff))
+			}
+		}
+	}
+	if !visible {
+		return ProminentColors{}, false
+	}
+	return GetImageColors(img).ProminentColorsWeighted(0, sm), true
+}
+
+// AnalyzeGIF - AnalyzeFrames for a GIF
+func AnalyzeGIF(g *gif.GIF, opts AnimationOptions) (*AnimationColors, error) {
+	return AnalyzeFrames(NewGIFFrames(g), opts)
+}
+
+// SceneCuts - Indexes of the frames whose palette Change is above threshold
+func (ac *AnimationColors) SceneCuts(threshold float64) (cuts []int) {
+	for i, f := range ac.Frames {
+		if f.Change > threshold {
+			cuts = append(cuts, i)
+		}
+	}
+	return cuts
+}
+
+// limitColors - ProminentColors with a weight above limit
+func limitColors(colors []ProminentColor, limit float64) (res []ProminentColor) {
+	for _, c := range colors {
+		if c.W > limit {
+			res = append(res, c)
+		}
+	}
+	return res
+}
+
+// paletteChange - Total variation distance between two color distributions
+func paletteChange(a, b map[MaterialColor]float64) float64 {
+	var sum float64
+	for mc, w := range a {
+		sum += math.Abs(w - b[mc])
+	}
+	for mc, w := range b {
+		if _, ok := a[mc]; !ok {
+			sum += w
+		}
+	}
+	return sum / 2
+}
@@ -0,0 +1,131 @@
+package imagecolor
+
+import (
+	"image"
+	"image/color"
+	"image/gif"
+	"io"
+	"math"
+	"testing"
+	"time"
+)
+
+var (
+	testRed  = color.RGBA{0xf4, 0x43, 0x36, 0xff}
+	testBlue = color.RGBA{0x21, 0x96, 0xf3, 0xff}
+)
+
+// testFrames - FrameIterator over a slice of frames
+type testFrames []Frame
+
+func (tf *testFrames) Next() (Frame, error) {
+	if len(*tf) == 0 {
+		return Frame{}, io.EOF
+	}
+	f := (*tf)[0]
+	*tf = (*tf)[1:]
+	return f, nil
+}
+
+// testFill - Opaque image filled with c
+func testFill(width, height int, c color.RGBA) *image.RGBA {
+	m := image.NewRGBA(image.Rect(0, 0, width, height))
+	for i := 0; i < len(m.Pix); i += 4 {
+		m.Pix[i], m.Pix[i+1], m.Pix[i+2], m.Pix[i+3] = c.R, c.G, c.B, c.A
+	}
+	return m
+}
+
+// testSticker - Paletted frame with an opaque square of c on a transparent background
+func testSticker(size int, square image.Rectangle, c color.Color) *image.Paletted {
+	m := image.NewPaletted(image.Rect(0, 0, size, size), color.Palette{color.Transparent, c})
+	for y := square.Min.Y; y < square.Max.Y; y++ {
+		for x := square.Min.X; x < square.Max.X; x++ {
+			m.SetColorIndex(x, y, 1)
+		}
+	}
+	return m
+}
+
+func TestAnalyzeGIFTransparent(t *testing.T) {
+	g := &gif.GIF{
+		Image:    []*image.Paletted{testSticker(40, image.Rect(10, 10, 30, 30), testRed), testSticker(40, image.Rect(5, 5, 15, 15), testRed)},
+		Delay:    []int{10, 10},
+		Disposal: []byte{gif.DisposalBackground, gif.DisposalBackground},
+	}
+	for _, size := range []int{0, 16} {
+		ac, err := AnalyzeGIF(g, AnimationOptions{Limit: 0.01, Size: size})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ac.Palette) != 1 || ac.Palette[0].Color != materialRed {
+			t.Errorf("Palette with size %d was incorrect, got: %v, want: %v.", size, ac.Palette, materialRed)
+		} else if math.Abs(ac.Palette[0].W-1) > 0.01 {
+			t.Errorf("Palette weight with size %d was incorrect, got: %v, want: %v.", size, ac.Palette[0].W, 1)
+		}
+		for i, f := range ac.Frames {
+			for _, c := range f.Colors.Colors {
+				if c.Color == materialBlack {
+					t.Errorf("Frame %d with size %d has transparent pixels counted as %v.", i, size, c.Color)
+				}
+			}
+		}
+	}
+}
+
+func TestAnalyzeFramesZeroDelay(t *testing.T) {
+	frames := testFrames{
+		{Image: testFill(10, 10, testRed)},
+		{Image: testFill(10, 10, testBlue)},
+		{Image: image.NewRGBA(image.Rect(0, 0, 10, 10))}, // fully transparent
+	}
+	ac, err := AnalyzeFrames(&frames, AnimationOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ac.Duration != 3*defaultFrameDelay {
+		t.Errorf("Duration was incorrect, got: %v, want: %v.", ac.Duration, 3*defaultFrameDelay)
+	}
+	if len(ac.Palette) != 2 {
+		t.Fatalf("Palette was incorrect, got: %v, want: %v colors.", ac.Palette, 2)
+	}
+	for _, c := range ac.Palette {
+		if math.IsNaN(c.W) || math.Abs(c.W-0.5) > 0.01 {
+			t.Errorf("Palette weight of %v was incorrect, got: %v, want: %v.", c.Color, c.W, 0.5)
+		}
+	}
+	if n := len(ac.Frames[2].Colors.Colors); n != 0 {
+		t.Errorf("Colors of a transparent frame was incorrect, got: %v, want: %v.", n, 0)
+	}
+}
+
+func TestSceneCuts(t *testing.T) {
+	frames := testFrames{
+		{Image: testFill(10, 10, testRed), Delay: time.Second},
+		{Image: testFill(10, 10, testRed), Delay: time.Second},
+		{Image: testFill(10, 10, testBlue), Delay: 2 * time.Second},
+	}
+	ac, err := AnalyzeFrames(&frames, AnimationOptions{Limit: 0.01})
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes := []float64{0, 0, 1}
+	for i, f := range ac.Frames {
+		if math.Abs(f.Change-changes[i]) > 1e-9 {
+			t.Errorf("Change of frame %d was incorrect, got: %v, want: %v.", i, f.Change, changes[i])
+		}
+	}
+	if cuts := ac.SceneCuts(0.5); len(cuts) != 1 || cuts[0] != 2 {
+		t.Errorf("SceneCuts was incorrect, got: %v, want: %v.", cuts, []int{2})
+	}
+	for _, c := range ac.Palette {
+		if math.Abs(c.W-0.5) > 0.01 {
+			t.Errorf("Palette weight of %v was incorrect, got: %v, want: %v.", c.Color, c.W, 0.5)
+		}
+	}
+
+	empty := testFrames{}
+	if _, err := AnalyzeFrames(&empty, AnimationOptions{}); err == nil || err.Error() != ErrorNoFrames {
+		t.Errorf("AnalyzeFrames error was incorrect, got: %v, want: %v.", err, ErrorNoFrames)
+	}
+}
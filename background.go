@@ -0,0 +1,197 @@
+package imagecolor
+
+import (
+	"image"
+	"math"
+	"sort"
+)
+
+// Background Defaults
+const (
+	defaultBorderWidth        = 0.05 // fraction of the smallest image dimension
+	defaultClusterDistance    = 10.0 // CIEDE2000
+	defaultForegroundDistance = 12.0 // CIEDE2000
+	backgroundMinWeight       = 0.1
+	backgroundIterations      = 3
+	maxBackgroundClusters     = 32 // border colors that fit no cluster are left unexplained
+)
+
+// BackgroundOptions - Options for EstimateBackground.
+// Zero values are replaced with defaults.
+type BackgroundOptions struct {
+	// BorderWidth is the width of the sampled border as a fraction of the smallest image dimension.
+	BorderWidth float64
+	// ClusterDistance is the maximum perceptual distance (CIEDE2000) between colors of a cluster.
+	ClusterDistance float64
+	// ForegroundDistance is the minimum perceptual distance (CIEDE2000) from every background
+	// color for a pixel to be considered foreground.
+	ForegroundDistance float64
+}
+
+// BackgroundColor - Estimated background color and the fraction of border pixels it covers
+type BackgroundColor struct {
+	Color ColorHSL
+	W     float64
+}
+
+// Background - Estimated background of an image
+type Background struct {
+	// Colors are the background colors sorted by weight, largest first.
+	Colors []BackgroundColor
+	// Confidence is the fraction of border pixels explained by the background colors,
+	// lowered when the background colors also cover the center of the image.
+	Confidence float64
+	// Foreground is opaque where pixels differ from every background color.
+	Foreground *image.Alpha
+}
+
+// DistancePerceptual - Perceptual distance (CIEDE2000) between 2 Colors in the HSL colorspace.
+// Reported on the usual 0-100 scale, where about 2 is a just noticeable difference.
+func (c ColorHSL) DistancePerceptual(c2 ColorHSL) float64 {
+	return c.Colorful().DistanceCIEDE2000(c2.Colorful()) * 100
+}
+
+// backgroundCluster - Cluster of border colors
+type backgroundCluster struct {
+	center ColorHSL
+	acc    colorAccumulator
+}
+
+// backgroundBin - Mean color of the border pixels in a cell of the RGB lookup cube
+type backgroundBin struct {
+	index int
+	color ColorHSL
+	n     float64
+}
+
+// EstimateBackground - Estimate the background color(s) of ImageColors from its border pixels.
+// Border pixels are binned in the cells of the 32x32x32 RGB lookup cube, and the bins are
+// clustered by perceptual distance, most common first, into at most 32 clusters;
+// clusters covering at least 10% of the border are reported as background colors.
+func (ic ImageColors) EstimateBackground(opts BackgroundOptions) Background {
+	if opts.BorderWidth <= 0 {
+		opts.BorderWidth = defaultBorderWidth
+	}
+	if opts.ClusterDistance <= 0 {
+		opts.ClusterDistance = defaultClusterDistance
+	}
+	if opts.ForegroundDistance <= 0 {
+		opts.ForegroundDistance = defaultForegroundDistance
+	}
+	width := len(ic)
+	height := 0
+	if width > 0 {
+		height = len(ic[0])
+	}
+	var bg Background
+	bg.Foreground = image.NewAlpha(image.Rect(0, 0, width, height))
+	if width == 0 || height == 0 {
+		return bg
+	}
+
+	border := maxInt(int(math.Round(opts.BorderWidth*float64(minInt(width, height)))), 1)
+	cells := make(map[int]*colorAccumulator)
+	var total float64
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			if x < border || y < border || x >= width-border || y >= height-border {
+				c := ic[x][y].Colorful()
+				i := cubeIndex(cubeCell(c.R), cubeCell(c.G), cubeCell(c.B))
+				acc, ok := cells[i]
+				if !ok {
+					acc = &colorAccumulator{mode: LinearAveraging}
+					cells[i] = acc
+				}
+				acc.add(c, 1)
+				total++
+			}
+		}
+	}
+	samples := make([]backgroundBin, 0, len(cells))
+	for i, acc := range cells {
+		samples = append(samples, backgroundBin{index: i, color: NewColorHSL(acc.mean()), n: acc.n})
+	}
+	sort.Slice(samples, func(i, j int) bool {
+		if samples[i].n != samples[j].n {
+			return samples[i].n > samples[j].n
+		}
+		return samples[i].index < samples[j].index
+	})
+
+	// Leader clustering followed by a few k-means style refinements
+	var clusters []*backgroundCluster
+	for iter := 0; iter < backgroundIterations; iter++ {
+		for _, cl := range clusters {
+			cl.acc = colorAccumulator{mode: LinearAveraging}
+		}
+		for _, s := range samples {
+			best, bestDist := -1, opts.ClusterDistance
+			for i, cl := range clusters {
+				if d := s.color.DistancePerceptual(cl.center); d < bestDist {
+					best, bestDist = i, d
+				}
+			}
+			if best < 0 {
+				if iter > 0 || len(clusters) == maxBackgroundClusters {
+					continue
+				}
+				clusters = append(clusters, &backgroundCluster{center: s.color, acc: colorAccumulator{mode: LinearAveraging}})
+				best = len(clusters) - 1
+			}
+			clusters[best].acc.add(s.color.Colorful(), s.n)
+		}
+		for _, cl := range clusters {
+			if cl.acc.n > 0 {
+				cl.center = NewColorHSL(cl.acc.mean())
+			}
+		}
+	}
+
+	var explained float64
+	for _, cl := range clusters {
+		w := cl.acc.n / total
+		if w >= backgroundMinWeight {
+			bg.Colors = append(bg.Colors, BackgroundColor{Color: cl.center, W: w})
+			explained += w
+		}
+	}
+	sort.Slice(bg.Colors, func(i, j int) bool { return bg.Colors[i].W > bg.Colors[j].W })
+
+	// Foreground mask
+	var center, centerBackground float64
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			foreground := true
+			for _, c := range bg.Colors {
+				if ic[x][y].DistancePerceptual(c.Color) < opts.ForegroundDistance {
+					foreground = false
+					break
+				}
+			}
+			if foreground {
+				bg.Foreground.Pix[y*bg.Foreground.Stride+x] = 0xff
+			}
+			if x >= width/4 && x < width*3/4 && y >= height/4 && y < height*3/4 {
+				center++
+				if !foreground {
+					centerBackground++
+				}
+			}
+		}
+	}
+
+	// A background that also fills the center of the image is likely part of the subject
+	bg.Confidence = explained
+	if center > 0 {
+		bg.Confidence *= 1 - 0.5*centerBackground/center
+	}
+	return bg
+}
+
+// IsWhite - Reports whether the dominant background color is within maxDistance (CIEDE2000) of white
+func (bg Background) IsWhite(maxDistance float64) bool {
+	if len(bg.Colors) == 0 {
+		return false
+	}
+	return bg.Colors[0].Color.DistancePerceptual(ColorHSL{0, 0, 1}) <= maxDistance
+}
@@ -0,0 +1,80 @@
+package imagecolor
+
+import (
+	"image"
+	"image/color"
+	"reflect"
+	"testing"
+)
+
+// testSubject - White image with a red square in its center
+func testSubject(width, height int) *ImageColors {
+	m := testFill(width, height, color.RGBA{0xff, 0xff, 0xff, 0xff})
+	for y := height / 3; y < height*2/3; y++ {
+		for x := width / 3; x < width*2/3; x++ {
+			m.SetRGBA(x, y, testRed)
+		}
+	}
+	return GetImageColors(m)
+}
+
+// testNoise - Image of pseudo random colors
+func testNoise(width, height int) *ImageColors {
+	m := image.NewRGBA(image.Rect(0, 0, width, height))
+	seed := uint32(1)
+	for i := range m.Pix {
+		seed = seed*1664525 + 1013904223
+		m.Pix[i] = uint8(seed >> 24)
+		if i%4 == 3 {
+			m.Pix[i] = 0xff
+		}
+	}
+	return GetImageColors(m)
+}
+
+func TestEstimateBackground(t *testing.T) {
+	ic := testSubject(60, 45)
+	bg := ic.EstimateBackground(BackgroundOptions{})
+	if len(bg.Colors) != 1 || bg.Colors[0].W != 1 || !bg.IsWhite(1) {
+		t.Fatalf("Colors was incorrect, got: %+v, want: %v.", bg.Colors, "white covering the border")
+	}
+	if bg.Confidence <= 0.5 || bg.Confidence > 1 {
+		t.Errorf("Confidence was incorrect, got: %v, want: in (0.5, 1].", bg.Confidence)
+	}
+	if bg.Foreground.Bounds() != image.Rect(0, 0, 60, 45) || bg.Foreground.AlphaAt(30, 22).A != 0xff || bg.Foreground.AlphaAt(2, 2).A != 0 {
+		t.Errorf("Foreground was incorrect, got: %v %v, want: subject opaque and background transparent.", bg.Foreground.AlphaAt(30, 22), bg.Foreground.AlphaAt(2, 2))
+	}
+
+	// Two background colors, the larger first
+	m := testFill(40, 40, testBlue)
+	for y := 0; y < 40; y++ {
+		for x := 0; x < 12; x++ {
+			m.SetRGBA(x, y, color.RGBA{0xff, 0xff, 0xff, 0xff})
+		}
+	}
+	bg = GetImageColors(m).EstimateBackground(BackgroundOptions{})
+	if len(bg.Colors) != 2 || bg.Colors[0].W <= bg.Colors[1].W || bg.IsWhite(1) {
+		t.Errorf("Colors was incorrect, got: %+v, want: %v.", bg.Colors, "blue then white")
+	}
+
+	if bg := (ImageColors{}).EstimateBackground(BackgroundOptions{}); len(bg.Colors) != 0 || bg.Confidence != 0 {
+		t.Errorf("Empty background was incorrect, got: %+v, want: %v.", bg, "no colors")
+	}
+}
+
+func TestEstimateBackgroundNoise(t *testing.T) {
+	// Binned and capped clustering of a border of distinct colors is deterministic and
+	// finds no background covering most of the border
+	ic := testNoise(200, 150)
+	bg := ic.EstimateBackground(BackgroundOptions{})
+	if again := ic.EstimateBackground(BackgroundOptions{}); !reflect.DeepEqual(bg.Colors, again.Colors) {
+		t.Errorf("Colors was not deterministic, got: %+v and %+v.", bg.Colors, again.Colors)
+	}
+	var sum float64
+	for _, c := range bg.Colors {
+		sum += c.W
+	}
+	if sum > 1+1e-9 || bg.Confidence >= 0.5 {
+		t.Errorf("Noise background was incorrect, got: weights %v confidence %v, want: confidence below %v.", sum, bg.Confidence, 0.5)
+	}
+}
@@ -27,6 +27,7 @@ func NewBox(x1, y1, x2, y2 int) *Box {
 
 // ThirdsBoxes - Create boxes for thirds composition.
 // 4 Boxes with centers at 1/3 and 2/3 horizontal and vertical.
+// CompositionGrid.Boxes creates boxes of any image bounds and other guides.
 func ThirdsBoxes(width, height int) (boxes []*Box) {
 	x1, y1 := int(width/6), int(height/6)
 	boxes = append(boxes, NewBox(x1, y1, x1*3, y1*3))
@@ -50,14 +51,26 @@ func focusedPixels(val []float64) (float64, float64, float64, float64) {
 	return mean, std, skew, per
 }
 
-// MeanStd - Get Mean and StandardVariation for values
+// MeanStd - Get Mean and StandardVariation for values.
+// Both are 0 without values, the StandardVariation of a single value is 0.
 func (b *Box) MeanStd() {
-	b.MeanL, b.StdL = stat.MeanStdDev(b.values, nil)
+	switch len(b.values) {
+	case 0:
+		b.MeanL, b.StdL = 0, 0
+	case 1:
+		b.MeanL, b.StdL = b.values[0], 0
+	default:
+		b.MeanL, b.StdL = stat.MeanStdDev(b.values, nil)
+	}
 }
 
-// Skew - Get the skew of the normal distribution curve for values
+// Skew - Get the skew of the normal distribution curve for values.
+// Values without variance have a skew of 0.
 func (b *Box) Skew() {
-	b.SkewL = stat.Skew(b.values, nil)
+	b.SkewL = 0
+	if skew := stat.Skew(b.values, nil); len(b.values) > 1 && !math.IsNaN(skew) && !math.IsInf(skew, 0) {
+		b.SkewL = skew
+	}
 }
 
 // FocusedPixels - Create ImageColors array from an image
@@ -79,7 +92,13 @@ func (b *Box) FocusedPixels(m image.Image) {
 	b.Focused = float64(len(b.values)) / float64(width*height)
 }
 
-// FocusScore -
+// FocusScore - Experimental, uncalibrated score.
+// MeasureSharpness and DetectBlur compute established focus measures.
+// Boxes without focused pixels score 0.
 func (b *Box) FocusScore() {
+	if b.MeanL == 0 {
+		b.Score = 0
+		return
+	}
 	b.Score = (math.Sqrt(b.MeanL*b.MeanL*b.StdL) - b.SkewL*b.Focused/(b.MeanL*10000)) * 10
 }
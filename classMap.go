@@ -0,0 +1,90 @@
+package imagecolor
+
+import (
+	"image"
+	"image/color"
+)
+
+// ColorClassMap - MaterialColor assigned to every pixel of an ImageColors.
+// Used for visualising how pixels were classified by ProminentColors.
+type ColorClassMap struct {
+	Width   int
+	Height  int
+	classes []MaterialColor // row major, y*Width + x
+}
+
+// ClassMap - Classify every pixel of ImageColors using closestMaterialColor
+func (ic ImageColors) ClassMap() *ColorClassMap {
+	return ic.ClassMapPalette(nil)
+}
+
+// ClassMapPalette - Classify every pixel of ImageColors with a Palette, such as the
+// Palette of an Analyzer. nil uses every Material color series like ClassMap.
+func (ic ImageColors) ClassMapPalette(palette *Palette) *ColorClassMap {
+	if palette == nil {
+		palette = materialPalette
+	}
+	width := len(ic)
+	height := 0
+	if width > 0 {
+		height = len(ic[0])
+	}
+	cm := &ColorClassMap{Width: width, Height: height, classes: make([]MaterialColor, width*height)}
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			cm.classes[y*width+x] = palette.Classify(ic[x][y])
+		}
+	}
+	return cm
+}
+
+// At - MaterialColor of the pixel at x, y
+func (cm *ColorClassMap) At(x, y int) MaterialColor {
+	return cm.classes[y*cm.Width+x]
+}
+
+// Image - Image where every pixel is recolored to its MaterialColor.
+// The image palette is indexed by MaterialColor.
+func (cm *ColorClassMap) Image() *image.Paletted {
+	img := image.NewPaletted(image.Rect(0, 0, cm.Width, cm.Height), MaterialColorPalette())
+	for i, mc := range cm.classes {
+		img.Pix[i] = uint8(mc)
+	}
+	return img
+}
+
+// Mask - Alpha mask that is opaque where pixels are classified as the MaterialColor
+func (cm *ColorClassMap) Mask(mc MaterialColor) *image.Alpha {
+	mask := image.NewAlpha(image.Rect(0, 0, cm.Width, cm.Height))
+	for i, c := range cm.classes {
+		if c == mc {
+			mask.Pix[i] = 0xff
+		}
+	}
+	return mask
+}
+
+// Weights - Fraction of pixels classified as each MaterialColor
+func (cm *ColorClassMap) Weights() map[MaterialColor]float64 {
+	res := make(map[MaterialColor]float64)
+	if len(cm.classes) == 0 {
+		return res
+	}
+	for _, c := range cm.classes {
+		res[c]++
+	}
+	for c := range res {
+		res[c] /= float64(len(cm.classes))
+	}
+	return res
+}
+
+// MaterialColorPalette - color.Palette of the MaterialColors (500 series) indexed by MaterialColor.
+// NewMaterialPalette creates the Palette used to classify colors.
+func MaterialColorPalette() color.Palette {
+	p := make(color.Palette, len(materialColorsName))
+	for mc := range materialColorsName {
+		p[mc] = mc.Colorful().Clamped()
+	}
+	return p
+}
@@ -0,0 +1,91 @@
+package imagecolor
+
+import (
+	"bytes"
+	"image"
+	"math"
+	"testing"
+)
+
+func TestClassMap(t *testing.T) {
+	ic := testHalves(20, 10)
+	cm := ic.ClassMap()
+	if cm.Width != 20 || cm.Height != 10 {
+		t.Fatalf("Size was incorrect, got: %vx%v, want: %vx%v.", cm.Width, cm.Height, 20, 10)
+	}
+	if cm.At(0, 0) != materialRed || cm.At(9, 9) != materialRed || cm.At(10, 0) != materialBlue || cm.At(19, 9) != materialBlue {
+		t.Errorf("At was incorrect, got: %v %v %v %v, want: %v %v %v %v.", cm.At(0, 0), cm.At(9, 9), cm.At(10, 0), cm.At(19, 9), materialRed, materialRed, materialBlue, materialBlue)
+	}
+
+	img := cm.Image()
+	palette := MaterialColorPalette()
+	if img.Bounds() != image.Rect(0, 0, 20, 10) {
+		t.Errorf("Image bounds was incorrect, got: %v, want: %v.", img.Bounds(), image.Rect(0, 0, 20, 10))
+	}
+	for _, p := range []image.Point{{3, 4}, {15, 2}} {
+		mc := cm.At(p.X, p.Y)
+		if i := img.ColorIndexAt(p.X, p.Y); MaterialColor(i) != mc || img.At(p.X, p.Y) != palette[mc] {
+			t.Errorf("Image pixel %v was incorrect, got: %v, want: %v.", p, i, mc)
+		}
+	}
+
+	red, blue, green := cm.Mask(materialRed), cm.Mask(materialBlue), cm.Mask(materialGreen)
+	for x := 0; x < 20; x++ {
+		for y := 0; y < 10; y++ {
+			want := uint8(0)
+			if x < 10 {
+				want = 0xff
+			}
+			if a := red.AlphaAt(x, y).A; a != want {
+				t.Fatalf("Red mask at %v,%v was incorrect, got: %v, want: %v.", x, y, a, want)
+			}
+			if a := blue.AlphaAt(x, y).A; a != 0xff-want {
+				t.Fatalf("Blue mask at %v,%v was incorrect, got: %v, want: %v.", x, y, a, 0xff-want)
+			}
+			if a := green.AlphaAt(x, y).A; a != 0 {
+				t.Fatalf("Green mask at %v,%v was incorrect, got: %v, want: %v.", x, y, a, 0)
+			}
+		}
+	}
+
+	if w := cm.Weights(); len(w) != 2 || w[materialRed] != 0.5 || w[materialBlue] != 0.5 {
+		t.Errorf("Weights was incorrect, got: %v, want: %v.", w, "red and blue halves")
+	}
+}
+
+func TestClassMapPalette(t *testing.T) {
+	ic := testNoise(40, 30)
+	all := ic.ClassMap()
+	differ := 0
+	for _, palette := range []*Palette{NewMaterialPalette(100), NewMaterialPalette(900), NewMaterialPalette(300, 700)} {
+		cm := ic.ClassMapPalette(palette)
+		for x := 0; x < cm.Width; x++ {
+			for y := 0; y < cm.Height; y++ {
+				if want := palette.Classify((*ic)[x][y]); cm.At(x, y) != want {
+					t.Fatalf("At %v,%v was incorrect, got: %v, want: %v.", x, y, cm.At(x, y), want)
+				}
+				if cm.At(x, y) != all.At(x, y) {
+					differ++
+				}
+			}
+		}
+
+		// The Analyzer classifies its ClassMap and ProminentColors with the same Palette
+		a := &Analyzer{Palette: palette}
+		if got := a.ClassMap(*ic).Image(); !bytes.Equal(got.Pix, cm.Image().Pix) {
+			t.Errorf("Analyzer ClassMap was incorrect, got: %v, want: %v.", got.Pix, cm.Image().Pix)
+		}
+		weights := cm.Weights()
+		for _, c := range a.ProminentColorsFrom(*ic, 0).Colors {
+			if math.Abs(c.W-weights[c.Color]) > 1e-9 {
+				t.Errorf("Weight of %v was incorrect, got: %v, want: %v.", c.Color, weights[c.Color], c.W)
+			}
+		}
+	}
+	if differ == 0 {
+		t.Errorf("ClassMapPalette was incorrect, got: the classes of every palette, want: different classes.")
+	}
+	if cm := ic.ClassMapPalette(nil); cm.At(5, 5) != all.At(5, 5) {
+		t.Errorf("Default palette was incorrect, got: %v, want: %v.", cm.At(5, 5), all.At(5, 5))
+	}
+}
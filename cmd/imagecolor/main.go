@@ -0,0 +1,308 @@
+// Command imagecolor analyzes the colors of images.
+//
+// Arguments are image files, glob patterns or directories (walked recursively).
+// One record is written per image, in the order of the arguments, as JSON Lines or CSV.
+//
+//	imagecolor -format csv -size 128 -limit 0.02 photos/ 'dump/*.jpg'
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
+	"log"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/evanoberholster/imageColor"
+	"github.com/evanoberholster/imageColor/internal/files"
+	"github.com/evanoberholster/imageColor/internal/imageutil"
+	"github.com/evanoberholster/imageColor/jpegdc"
+	"github.com/evanoberholster/imageColor/loader"
+)
+
+// options - Command line flags
+type options struct {
+	size    int
+	limit   float64
+	palette *imagecolor.Palette
+	format  string
+	workers int
+	jpegDC  bool
+}
+
+// Record - Analysis of a single image
+type Record struct {
+	File         string                      `json:"file"`
+	Width        int                         `json:"width"`
+	Height       int                         `json:"height"`
+	Colors       []imagecolor.ProminentColor `json:"colors"`
+	Hue          [2]float64                  `json:"hue"`
+	Saturation   [2]float64                  `json:"saturation"`
+	Lightness    [2]float64                  `json:"lightness"`
+	Colorfulness float64                     `json:"colorfulness"`
+	Qlightness   float64                     `json:"qlightness"`
+	Error        string                      `json:"error,omitempty"`
+}
+
+func main() {
+	var opts options
+	var palette string
+	flag.IntVar(&opts.size, "size", 256, "downscale images so that their largest side is at most `pixels` (0 keeps the image size)")
+	flag.Float64Var(&opts.limit, "limit", 0.01, "minimum `weight` of a prominent color")
+	flag.StringVar(&palette, "palette", "all", "Material color `series` used for classification: all, or a comma separated list of 100, 300, 500, 700 and 900")
+	flag.StringVar(&opts.format, "format", "jsonl", "output `format`: jsonl or csv")
+	flag.IntVar(&opts.workers, "workers", runtime.NumCPU(), "number of images analyzed concurrently")
+	flag.BoolVar(&opts.jpegDC, "jpeg-dc", true, "decode large JPEGs at 1/8 scale from their DC coefficients when the result is at least -size pixels")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] file|glob|dir...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	log.SetFlags(0)
+
+	var err error
+	if opts.palette, err = parsePalette(palette); err != nil {
+		log.Fatal(err)
+	}
+	if opts.format != "jsonl" && opts.format != "csv" {
+		log.Fatalf("unknown format %q", opts.format)
+	}
+	if opts.workers < 1 {
+		opts.workers = 1
+	}
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	paths, err := files.Expand(flag.Args())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	failed, err := run(os.Stdout, paths, opts)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if failed > 0 {
+		log.Printf("%d of %d images could not be analyzed", failed, len(paths))
+		os.Exit(1)
+	}
+}
+
+// parsePalette - Palette of the Material color series listed in s
+func parsePalette(s string) (*imagecolor.Palette, error) {
+	if s == "" || s == "all" {
+		return imagecolor.NewMaterialPalette(), nil
+	}
+	var series []int
+	for _, f := range strings.Split(s, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(f))
+		if err != nil || imagecolor.MaterialSeriesPalette(n) == nil {
+			return nil, fmt.Errorf("unknown palette series %q", f)
+		}
+		series = append(series, n)
+	}
+	return imagecolor.NewMaterialPalette(series...), nil
+}
+
+// job - File to analyze and its position in the output
+type job struct {
+	i    int
+	file string
+}
+
+// result - Record and its position in the output
+type result struct {
+	i      int
+	record Record
+}
+
+// run - Analyze paths with a pool of workers and write the records in order.
+// Returns the number of images that could not be analyzed.
+func run(w io.Writer, paths []string, opts options) (int, error) {
+	jobs := make(chan job)
+	results := make(chan result)
+	for n := 0; n < opts.workers; n++ {
+		go func() {
+			a := &imagecolor.Analyzer{Palette: opts.palette}
+			for j := range jobs {
+				results <- result{j.i, analyze(a, j.file, opts)}
+			}
+		}()
+	}
+	go func() {
+		for i, f := range paths {
+			jobs <- job{i, f}
+		}
+		close(jobs)
+	}()
+
+	out := newWriter(w, opts.format)
+	pending := make(map[int]Record)
+	failed := 0
+	for next := 0; next < len(paths); {
+		r := <-results
+		pending[r.i] = r.record
+		for rec, ok := pending[next]; ok; rec, ok = pending[next] {
+			delete(pending, next)
+			next++
+			err := out.write(rec)
+			var ee *encodeError
+			if errors.As(err, &ee) {
+				// Only this record fails, the others are still written
+				rec = Record{File: rec.File, Error: ee.Error()}
+				err = out.write(rec)
+			}
+			if rec.Error != "" {
+				failed++
+			}
+			if err != nil {
+				return failed, err
+			}
+		}
+	}
+	return failed, out.flush()
+}
+
+// analyze - Record of a single image
+func analyze(a *imagecolor.Analyzer, file string, opts options) Record {
+	rec := Record{File: file}
+	img, width, height, err := open(file, opts)
+	if err != nil {
+		rec.Error = err.Error()
+		return rec
+	}
+	rec.Width, rec.Height = width, height
+	img = imageutil.Downscale(img, opts.size)
+
+	ic := a.ImageColors(img)
+	pc := a.ProminentColorsFrom(ic, opts.limit)
+	hue, hueStd := ic.MeanHue()
+	rec.Colors = pc.Colors
+	for i := range rec.Colors {
+		rec.Colors[i].W = imageutil.Finite(rec.Colors[i].W)
+	}
+	// Statistics of a single pixel have a NaN standard deviation
+	rec.Hue = [2]float64{imageutil.Finite(hue), imageutil.Finite(hueStd)}
+	rec.Saturation = [2]float64{imageutil.Finite(pc.Saturation[0]), imageutil.Finite(pc.Saturation[1])}
+	rec.Lightness = [2]float64{imageutil.Finite(pc.Lightness[0]), imageutil.Finite(pc.Lightness[1])}
+	rec.Colorfulness = imageutil.Finite(pc.Colorfulness)
+	rec.Qlightness = imageutil.Finite(pc.Qlightness)
+	return rec
+}
+
+// open - Decode an image file, oriented for display, with its full size.
+// JPEGs that are at least 8 times larger than -size are decoded from their DC
+// coefficients, other files and unsupported JPEGs are fully decoded.
+func open(file string, opts options) (img image.Image, width, height int, err error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	if opts.jpegDC && opts.size > 0 {
+		cfg, err := jpeg.DecodeConfig(bytes.NewReader(data))
+		if err == nil && imageutil.MaxInt(cfg.Width, cfg.Height)/jpegdc.Scale >= opts.size {
+			if img, err := jpegdc.DecodeBytes(data); err == nil {
+				o := loader.Orientation(data, "jpeg")
+				if o >= 5 {
+					cfg.Width, cfg.Height = cfg.Height, cfg.Width
+				}
+				return loader.Orient(img, o), cfg.Width, cfg.Height, nil
+			}
+		}
+	}
+	img, md, err := loader.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("%s: %w", file, err)
+	}
+	return img, md.Width, md.Height, nil
+}
+
+// writer - Writes Records as JSON Lines or CSV
+type writer struct {
+	json   io.Writer
+	csv    *csv.Writer
+	header bool
+}
+
+// encodeError - A record that cannot be encoded, nothing of it was written
+type encodeError struct{ err error }
+
+func (e *encodeError) Error() string { return e.err.Error() }
+
+// csvColumns - Columns of the CSV output
+var csvColumns = []string{
+	"file", "width", "height", "colors",
+	"hue_mean", "hue_std", "saturation_mean", "saturation_std", "lightness_mean", "lightness_std",
+	"colorfulness", "qlightness", "error",
+}
+
+func newWriter(w io.Writer, format string) *writer {
+	if format == "csv" {
+		return &writer{csv: csv.NewWriter(w)}
+	}
+	return &writer{json: w}
+}
+
+// write - Write rec, an encodeError when rec cannot be encoded
+func (w *writer) write(rec Record) error {
+	if w.json != nil {
+		b, err := json.Marshal(rec)
+		if err != nil {
+			return &encodeError{err}
+		}
+		_, err = w.json.Write(append(b, '\n'))
+		return err
+	}
+	colors := make([]string, len(rec.Colors))
+	for i, c := range rec.Colors {
+		text, err := c.MarshalText()
+		if err != nil {
+			return &encodeError{err}
+		}
+		colors[i] = string(text)
+	}
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+	return w.csv.Write([]string{
+		rec.File, strconv.Itoa(rec.Width), strconv.Itoa(rec.Height), strings.Join(colors, " "),
+		formatFloat(rec.Hue[0]), formatFloat(rec.Hue[1]),
+		formatFloat(rec.Saturation[0]), formatFloat(rec.Saturation[1]),
+		formatFloat(rec.Lightness[0]), formatFloat(rec.Lightness[1]),
+		formatFloat(rec.Colorfulness), formatFloat(rec.Qlightness), rec.Error,
+	})
+}
+
+// writeHeader - Write the CSV header before the first record
+func (w *writer) writeHeader() error {
+	if w.csv == nil || w.header {
+		return nil
+	}
+	w.header = true
+	return w.csv.Write(csvColumns)
+}
+
+func (w *writer) flush() error {
+	if w.csv == nil {
+		return nil
+	}
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+	w.csv.Flush()
+	return w.csv.Error()
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', 4, 64)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/evanoberholster/imageColor"
+)
+
+// writePNG - Write a width x height PNG of a single color to dir
+func writePNG(t *testing.T, dir, name string, width, height int) string {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{200, 30, 30, 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	bad := filepath.Join(dir, "bad.png")
+	if err := os.WriteFile(bad, []byte("not an image"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	paths := []string{
+		writePNG(t, dir, "pixel.png", 1, 1),
+		bad,
+		writePNG(t, dir, "red.png", 30, 20),
+	}
+
+	for _, format := range []string{"jsonl", "csv"} {
+		opts := options{size: 256, limit: 0.01, palette: imagecolor.NewMaterialPalette(), format: format, workers: 2}
+		var out bytes.Buffer
+		failed, err := run(&out, paths, opts)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if failed != 1 {
+			t.Errorf("%s failed was incorrect, got: %v, want: %v.", format, failed, 1)
+		}
+		if strings.Contains(out.String(), "NaN") {
+			t.Errorf("%s output was incorrect, got: %s, want: no NaN.", format, out.String())
+		}
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		if format == "csv" {
+			lines = lines[1:] // header
+		}
+		if len(lines) != len(paths) {
+			t.Fatalf("%s records was incorrect, got: %v, want: %v.", format, len(lines), len(paths))
+		}
+		if format == "jsonl" {
+			scanner := bufio.NewScanner(&out)
+			for i := 0; scanner.Scan(); i++ {
+				var rec Record
+				if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+					t.Fatal(err)
+				}
+				if rec.File != paths[i] || (rec.Error != "") != (paths[i] == bad) {
+					t.Errorf("Record %d was incorrect, got: %v %q, want: %v.", i, rec.File, rec.Error, paths[i])
+				}
+			}
+		}
+	}
+}
+
+func TestWriterEncodeError(t *testing.T) {
+	var out bytes.Buffer
+	w := newWriter(&out, "jsonl")
+	err := w.write(Record{File: "nan.png", Hue: [2]float64{math.NaN(), 0}})
+	var ee *encodeError
+	if !errors.As(err, &ee) || out.Len() != 0 {
+		t.Errorf("Write was incorrect, got: %v %q, want: an encodeError and no output.", err, out.String())
+	}
+	if err := w.write(Record{File: "ok.png"}); err != nil || !strings.HasPrefix(out.String(), `{"file":"ok.png"`) {
+		t.Errorf("Write was incorrect, got: %v %q, want: %v.", err, out.String(), "ok.png")
+	}
+}
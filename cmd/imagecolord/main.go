@@ -0,0 +1,60 @@
+// Command imagecolord serves color analysis, perceptual hashes, composition scores and
+// blur detection of images over HTTP. Results are JSON.
+//
+//	imagecolord -addr :8080 -root /srv/photos -concurrency 4 -timeout 10s
+//
+//	curl --data-binary @photo.jpg localhost:8080/colors
+//	curl -F image=@photo.jpg 'localhost:8080/hash?kind=phash'
+//	curl 'localhost:8080/composition?path=2020/photo.jpg'
+//	curl --data-binary @upload.jpg 'localhost:8080/blur?measure=laplacian'
+//	curl localhost:8080/health
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"runtime"
+	"syscall"
+	"time"
+)
+
+func main() {
+	var cfg Config
+	addr := flag.String("addr", ":8080", "listen `address`")
+	flag.Int64Var(&cfg.MaxBytes, "max-bytes", defaultMaxBytes, "maximum size of an image in `bytes`")
+	flag.IntVar(&cfg.MaxPixels, "max-pixels", defaultMaxPixels, "maximum width*height of an image")
+	flag.DurationVar(&cfg.Timeout, "timeout", defaultTimeout, "maximum `duration` of a request")
+	flag.IntVar(&cfg.Concurrency, "concurrency", runtime.NumCPU(), "maximum number of images analyzed at once")
+	flag.IntVar(&cfg.Size, "size", defaultSize, "default and maximum largest side in `pixels` of images analyzed by /colors")
+	flag.Float64Var(&cfg.Limit, "limit", defaultLimit, "default minimum `weight` of a prominent color")
+	flag.StringVar(&cfg.Root, "root", "", "`directory` of images that can be requested with ?path= (disabled when empty)")
+	flag.Parse()
+	log.SetFlags(log.LstdFlags)
+
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           NewServer(cfg),
+		ReadHeaderTimeout: 10 * time.Second,
+		// TimeoutHandler answers after cfg.Timeout, leave time to write the response
+		WriteTimeout: cfg.Timeout + 5*time.Second,
+	}
+	idle := make(chan struct{})
+	go func() {
+		defer close(idle)
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+		<-sig
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+		defer cancel()
+		srv.Shutdown(ctx)
+	}()
+	log.Printf("listening on %s", *addr)
+	if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
+	<-idle
+}
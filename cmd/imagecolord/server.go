@@ -0,0 +1,437 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"math"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/evanoberholster/imageColor"
+	"github.com/evanoberholster/imageColor/hash"
+	"github.com/evanoberholster/imageColor/internal/imageutil"
+	"github.com/evanoberholster/imageColor/loader"
+)
+
+// Server Defaults
+const (
+	defaultMaxBytes  = 32 << 20
+	defaultMaxPixels = 50000000
+	defaultTimeout   = 30 * time.Second
+	defaultSize      = 256
+	defaultLimit     = 0.01
+	compositionSize  = 500 // width used by CalcCompositionBoxes
+)
+
+// Config - Server limits and analysis defaults. Zero values use the defaults.
+type Config struct {
+	MaxBytes    int64         // maximum size of an upload or file
+	MaxPixels   int           // maximum width*height of an image, checked before decoding
+	Timeout     time.Duration // maximum duration of a request
+	Concurrency int           // maximum number of images analyzed at once, defaults to NumCPU
+	Size        int           // default and maximum largest side of downscaled images for /colors
+	Limit       float64       // default minimum weight of a prominent color for /colors
+
+	// Root is the directory of images that can be requested with ?path=.
+	// Paths are resolved inside Root, also through symlinks; path requests are disabled when Root is empty.
+	Root string
+}
+
+// Server - HTTP analysis service.
+//
+//	GET  /health
+//	POST /colors?limit=0.01&size=256
+//	POST /hash?kind=ahash,dhash,phash
+//	POST /composition?grid=thirds
+//	POST /blur?measure=laplacian&threshold=100
+//
+// Images are uploaded as the request body, or as the "image" field of a multipart form.
+// GET and POST requests with ?path= read the image from Config.Root instead.
+type Server struct {
+	cfg     Config
+	sem     chan struct{}
+	mux     *http.ServeMux
+	handler http.Handler
+	loader  loader.Loader
+	pool    sync.Pool
+}
+
+// NewServer - Create a Server
+func NewServer(cfg Config) *Server {
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = defaultMaxBytes
+	}
+	if cfg.MaxPixels <= 0 {
+		cfg.MaxPixels = defaultMaxPixels
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = runtime.NumCPU()
+	}
+	if cfg.Size <= 0 {
+		cfg.Size = defaultSize
+	}
+	if cfg.Limit <= 0 {
+		cfg.Limit = defaultLimit
+	}
+	s := &Server{
+		cfg: cfg,
+		sem: make(chan struct{}, cfg.Concurrency),
+		mux: http.NewServeMux(),
+		loader: loader.Loader{
+			MaxBytes:  cfg.MaxBytes,
+			MaxPixels: cfg.MaxPixels,
+		},
+	}
+	s.pool.New = func() interface{} { return imagecolor.NewAnalyzer() }
+	s.mux.HandleFunc("/health", s.health)
+	s.mux.Handle("/colors", s.analysis(s.colors))
+	s.mux.Handle("/hash", s.analysis(s.hash))
+	s.mux.Handle("/composition", s.analysis(s.composition))
+	s.mux.Handle("/blur", s.analysis(s.blur))
+	s.handler = http.TimeoutHandler(s.mux, cfg.Timeout, `{"error":"request timed out"}`)
+	return s
+}
+
+// ServeHTTP - Serve a request
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Every response is JSON, including the timeout response of http.TimeoutHandler
+	w.Header().Set("Content-Type", "application/json")
+	s.handler.ServeHTTP(w, r)
+}
+
+// httpError - Error with its HTTP status code
+type httpError struct {
+	status int
+	err    error
+}
+
+func (e *httpError) Error() string { return e.err.Error() }
+
+func errorf(status int, format string, a ...interface{}) error {
+	return &httpError{status, fmt.Errorf(format, a...)}
+}
+
+// analysisFunc - Analysis of a decoded image, the result is written as JSON
+type analysisFunc func(r *http.Request, img image.Image) (interface{}, error)
+
+// analysis - Handler that decodes the image of a request and runs fn on it.
+// At most Config.Concurrency analyses run at once, other requests wait for a slot.
+func (s *Server) analysis(fn analysisFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost && r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET, POST")
+			writeError(w, errorf(http.StatusMethodNotAllowed, "method %s not allowed", r.Method))
+			return
+		}
+		select {
+		case s.sem <- struct{}{}:
+			defer func() { <-s.sem }()
+		case <-r.Context().Done():
+			writeError(w, errorf(http.StatusServiceUnavailable, "server busy"))
+			return
+		}
+		// A timed out request stops before its next stage, so that it releases its slot
+		if err := canceled(r); err != nil {
+			writeError(w, err)
+			return
+		}
+		img, err := s.decode(r)
+		if err == nil {
+			err = canceled(r)
+		}
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		res, err := fn(r, img)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, res)
+	})
+}
+
+// canceled - Error when the request timed out or its client went away.
+// Analyses check it between stages, http.TimeoutHandler does not stop a running handler.
+func canceled(r *http.Request) error {
+	if r.Context().Err() != nil {
+		return errorf(http.StatusServiceUnavailable, "request timed out")
+	}
+	return nil
+}
+
+// decode - Image of a request, from ?path=, a multipart form or the request body
+func (s *Server) decode(r *http.Request) (image.Image, error) {
+	var img image.Image
+	var err error
+	switch p := r.URL.Query().Get("path"); {
+	case p != "":
+		img, err = s.decodeFile(p)
+	case r.Method != http.MethodPost:
+		return nil, errorf(http.StatusBadRequest, "missing image: POST an image or use ?path=")
+	default:
+		img, err = s.decodeBody(r)
+	}
+	var mbe *http.MaxBytesError
+	var he *httpError
+	switch {
+	case err == nil:
+	case errors.As(err, &he):
+		return nil, err
+	case errors.As(err, &mbe), errors.Is(err, loader.ErrTooLarge):
+		return nil, errorf(http.StatusRequestEntityTooLarge, "image is larger than %d bytes", s.cfg.MaxBytes)
+	case errors.Is(err, loader.ErrTooManyPixels):
+		return nil, errorf(http.StatusRequestEntityTooLarge, "image has more than %d pixels", s.cfg.MaxPixels)
+	case errors.Is(err, loader.ErrUnknownFormat):
+		return nil, errorf(http.StatusUnsupportedMediaType, "%v", err)
+	default:
+		return nil, errorf(http.StatusBadRequest, "decoding image: %v", err)
+	}
+	return img, nil
+}
+
+// decodeBody - Image uploaded as the request body or as the "image" field of a multipart form
+func (s *Server) decodeBody(r *http.Request) (image.Image, error) {
+	r.Body = http.MaxBytesReader(nil, r.Body, s.cfg.MaxBytes)
+	if mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mt != "multipart/form-data" {
+		img, _, err := s.loader.Decode(r.Body)
+		return img, err
+	}
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		part, err := mr.NextPart()
+		if err != nil {
+			return nil, errorf(http.StatusBadRequest, "missing form field %q", "image")
+		}
+		if part.FormName() == "image" {
+			img, _, err := s.loader.Decode(part)
+			return img, err
+		}
+	}
+}
+
+// decodeFile - Image at the slash separated path p inside Config.Root
+func (s *Server) decodeFile(p string) (image.Image, error) {
+	if s.cfg.Root == "" {
+		return nil, errorf(http.StatusForbidden, "path requests are disabled")
+	}
+	root, err := filepath.EvalSymlinks(s.cfg.Root)
+	if err != nil {
+		return nil, errorf(http.StatusNotFound, "image %q not found", p)
+	}
+	// Cleaning the path as if it were absolute removes any ".." that would leave Root,
+	// resolving its symlinks catches links that point outside of Root
+	file, err := filepath.EvalSymlinks(filepath.Join(root, filepath.FromSlash(path.Clean("/"+p))))
+	if err != nil || !inside(root, file) {
+		return nil, errorf(http.StatusNotFound, "image %q not found", p)
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, errorf(http.StatusNotFound, "image %q not found", p)
+	}
+	defer f.Close()
+	if info, err := f.Stat(); err != nil || info.IsDir() {
+		return nil, errorf(http.StatusNotFound, "image %q not found", p)
+	}
+	img, _, err := s.loader.Decode(f)
+	return img, err
+}
+
+// inside - Whether file is root or inside of it, both paths are clean
+func inside(root, file string) bool {
+	rel, err := filepath.Rel(root, file)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func (s *Server) health(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status": "ok",
+		"busy":   len(s.sem),
+		"slots":  cap(s.sem),
+	})
+}
+
+// ColorsResponse - Result of /colors
+type ColorsResponse struct {
+	Width        int                         `json:"width"`
+	Height       int                         `json:"height"`
+	Colors       []imagecolor.ProminentColor `json:"colors"`
+	Hue          [2]float64                  `json:"hue"`
+	Saturation   [2]float64                  `json:"saturation"`
+	Lightness    [2]float64                  `json:"lightness"`
+	Colorfulness float64                     `json:"colorfulness"`
+	Qlightness   float64                     `json:"qlightness"`
+}
+
+func (s *Server) colors(r *http.Request, img image.Image) (interface{}, error) {
+	q := r.URL.Query()
+	limit, size := s.cfg.Limit, s.cfg.Size
+	if v := q.Get("limit"); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || f < 0 || f >= 1 {
+			return nil, errorf(http.StatusBadRequest, "invalid limit %q", v)
+		}
+		limit = f
+	}
+	if v := q.Get("size"); v != "" {
+		// Downscaling is required, a full size image would grow the pooled Analyzer buffers
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > s.cfg.Size {
+			return nil, errorf(http.StatusBadRequest, "invalid size %q: must be between 1 and %d", v, s.cfg.Size)
+		}
+		size = n
+	}
+
+	b := img.Bounds()
+	res := ColorsResponse{Width: b.Dx(), Height: b.Dy()}
+	img = imageutil.Downscale(img, size)
+	if err := canceled(r); err != nil {
+		return nil, err
+	}
+	a := s.pool.Get().(*imagecolor.Analyzer)
+	defer s.pool.Put(a)
+	ic := a.ImageColors(img)
+	if err := canceled(r); err != nil {
+		return nil, err
+	}
+	pc := a.ProminentColorsFrom(ic, limit)
+	hue, hueStd := ic.MeanHue()
+	res.Colors = pc.Colors
+	for i := range res.Colors {
+		res.Colors[i].W = imageutil.Finite(res.Colors[i].W)
+	}
+	// Statistics of a single pixel have a NaN standard deviation
+	res.Hue = [2]float64{imageutil.Finite(hue), imageutil.Finite(hueStd)}
+	res.Saturation = [2]float64{imageutil.Finite(pc.Saturation[0]), imageutil.Finite(pc.Saturation[1])}
+	res.Lightness = [2]float64{imageutil.Finite(pc.Lightness[0]), imageutil.Finite(pc.Lightness[1])}
+	res.Colorfulness = imageutil.Finite(pc.Colorfulness)
+	res.Qlightness = imageutil.Finite(pc.Qlightness)
+	return res, nil
+}
+
+// hash - Hashes of the kinds listed in ?kind=, every kind by default
+func (s *Server) hash(r *http.Request, img image.Image) (interface{}, error) {
+	kinds := hash.Kinds
+	if v := r.URL.Query().Get("kind"); v != "" {
+		kinds = nil
+		for _, name := range strings.Split(v, ",") {
+			kind, err := hash.ParseKind(strings.TrimSpace(name))
+			if err != nil {
+				return nil, errorf(http.StatusBadRequest, "unknown hash kind %q", name)
+			}
+			kinds = append(kinds, kind)
+		}
+	}
+	res := make(map[string]string, len(kinds))
+	for _, kind := range kinds {
+		if err := canceled(r); err != nil {
+			return nil, err
+		}
+		h, err := hash.Hash(img, kind)
+		if err != nil {
+			return nil, err
+		}
+		res[kind.String()] = h.ToString()
+	}
+	return res, nil
+}
+
+// CompositionResponse - Result of /composition: the grid boxes followed by the whole image
+type CompositionResponse struct {
+	Width  int                         `json:"width"`
+	Height int                         `json:"height"`
+	Grid   imagecolor.CompositionGrid  `json:"grid"`
+	Boxes  []imagecolor.CompositionBox `json:"boxes"`
+}
+
+// composition - CalcCompositionBoxes with the grid of ?grid= (thirds by default),
+// on an image resized to compositionSize wide
+func (s *Server) composition(r *http.Request, img image.Image) (interface{}, error) {
+	var opts imagecolor.CompositionOptions
+	if v := r.URL.Query().Get("grid"); v != "" {
+		grid, err := imagecolor.ParseGrid(v)
+		if err != nil {
+			return nil, errorf(http.StatusBadRequest, "unknown grid %q", v)
+		}
+		opts.Grid = grid
+	}
+	if b := img.Bounds(); b.Dx() > compositionSize {
+		img = imagecolor.Downscale(img, compositionSize, imageutil.MaxInt(b.Dy()*compositionSize/b.Dx(), 1), imagecolor.GammaAveraging)
+	}
+	if err := canceled(r); err != nil {
+		return nil, err
+	}
+	report, err := imagecolor.CalcCompositionBoxes(img, opts)
+	if err != nil {
+		return nil, errorf(http.StatusBadRequest, "%v", err)
+	}
+	return CompositionResponse{
+		Width:  report.Width,
+		Height: report.Height,
+		Grid:   report.Grid,
+		Boxes:  append(report.Boxes, report.Image),
+	}, nil
+}
+
+// blur - DetectBlur with the measure and threshold of ?measure= and ?threshold=
+func (s *Server) blur(r *http.Request, img image.Image) (interface{}, error) {
+	q := r.URL.Query()
+	var opts imagecolor.BlurOptions
+	if v := q.Get("measure"); v != "" {
+		measure, err := imagecolor.ParseFocusMeasure(v)
+		if err != nil {
+			return nil, errorf(http.StatusBadRequest, "unknown focus measure %q", v)
+		}
+		opts.Measure = measure
+	}
+	if v := q.Get("threshold"); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || !(f > 0) || math.IsInf(f, 0) {
+			return nil, errorf(http.StatusBadRequest, "invalid threshold %q", v)
+		}
+		opts.Threshold = f
+	}
+	report, err := imagecolor.DetectBlur(img, opts)
+	if err != nil {
+		return nil, errorf(http.StatusBadRequest, "%v", err)
+	}
+	return report, nil
+}
+
+// writeJSON - Write v as JSON, or a 500 error when v cannot be encoded
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		status = http.StatusInternalServerError
+		b, _ = json.Marshal(map[string]string{"error": err.Error()})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(append(b, '\n'))
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	var he *httpError
+	if errors.As(err, &he) {
+		status = he.status
+	}
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/evanoberholster/imageColor"
+	"github.com/evanoberholster/imageColor/hash"
+)
+
+// testPNG - PNG encoded image with a red, a green and a blue band
+func testPNG(t *testing.T, width, height int) []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	bands := []color.NRGBA{{200, 30, 30, 255}, {30, 200, 30, 255}, {30, 30, 200, 255}}
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetNRGBA(x, y, bands[x*len(bands)/width])
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// do - Serve a request and decode its JSON response into v
+func do(t *testing.T, s http.Handler, r *http.Request, v interface{}) int {
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type was incorrect, got: %v, want: %v.", ct, "application/json")
+	}
+	if v != nil {
+		if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+			t.Fatalf("%s %s: %v: %s", r.Method, r.URL, err, w.Body)
+		}
+	}
+	return w.Code
+}
+
+func TestHealth(t *testing.T) {
+	s := NewServer(Config{Concurrency: 3})
+	var res struct {
+		Status string
+		Slots  int
+	}
+	if code := do(t, s, httptest.NewRequest("GET", "/health", nil), &res); code != http.StatusOK {
+		t.Errorf("Status code was incorrect, got: %v, want: %v.", code, http.StatusOK)
+	}
+	if res.Status != "ok" || res.Slots != 3 {
+		t.Errorf("Health was incorrect, got: %+v, want: %v.", res, "ok with 3 slots")
+	}
+}
+
+func TestColors(t *testing.T) {
+	s := NewServer(Config{})
+	data := testPNG(t, 90, 30)
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, err := mw.CreateFormFile("image", "test.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Write(data)
+	mw.Close()
+	form := httptest.NewRequest("POST", "/colors?size=30", &body)
+	form.Header.Set("Content-Type", mw.FormDataContentType())
+
+	requests := []*http.Request{
+		httptest.NewRequest("POST", "/colors", bytes.NewReader(data)),
+		form,
+	}
+	for _, r := range requests {
+		var res ColorsResponse
+		if code := do(t, s, r, &res); code != http.StatusOK {
+			t.Fatalf("Status code was incorrect, got: %v, want: %v.", code, http.StatusOK)
+		}
+		if res.Width != 90 || res.Height != 30 {
+			t.Errorf("Size was incorrect, got: %vx%v, want: %vx%v.", res.Width, res.Height, 90, 30)
+		}
+		if len(res.Colors) != 3 {
+			t.Errorf("Colors was incorrect, got: %v, want: %v.", res.Colors, "3 colors")
+		}
+	}
+}
+
+func TestColorsSinglePixel(t *testing.T) {
+	var res ColorsResponse
+	if code := do(t, NewServer(Config{}), httptest.NewRequest("POST", "/colors", bytes.NewReader(testPNG(t, 1, 1))), &res); code != http.StatusOK {
+		t.Fatalf("Status code was incorrect, got: %v, want: %v.", code, http.StatusOK)
+	}
+	if len(res.Colors) != 1 || res.Width != 1 {
+		t.Errorf("Colors was incorrect, got: %+v, want: %v.", res, "1 color")
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	w := httptest.NewRecorder()
+	writeJSON(w, http.StatusOK, map[string]float64{"nan": math.NaN()})
+	var res struct{ Error string }
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil || w.Code != http.StatusInternalServerError || res.Error == "" {
+		t.Errorf("Unencodable response was incorrect, got: %v %s, want: %v with an error.", w.Code, w.Body, http.StatusInternalServerError)
+	}
+}
+
+func TestHash(t *testing.T) {
+	s := NewServer(Config{})
+	data := testPNG(t, 120, 80)
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var res map[string]string
+	if code := do(t, s, httptest.NewRequest("POST", "/hash", bytes.NewReader(data)), &res); code != http.StatusOK {
+		t.Fatalf("Status code was incorrect, got: %v, want: %v.", code, http.StatusOK)
+	}
+	if len(res) != len(hash.Kinds) {
+		t.Errorf("Hashes was incorrect, got: %v, want: %v.", res, hash.Kinds)
+	}
+	for _, kind := range hash.Kinds {
+		h, err := hash.Hash(img, kind)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res[kind.String()] != h.ToString() {
+			t.Errorf("%v was incorrect, got: %v, want: %v.", kind, res[kind.String()], h.ToString())
+		}
+	}
+
+	res = nil
+	do(t, s, httptest.NewRequest("POST", "/hash?kind=dhash", bytes.NewReader(data)), &res)
+	if _, ok := res["dhash"]; !ok || len(res) != 1 {
+		t.Errorf("Hashes was incorrect, got: %v, want: %v.", res, "dhash")
+	}
+}
+
+func TestComposition(t *testing.T) {
+	s := NewServer(Config{})
+	var res CompositionResponse
+	if code := do(t, s, httptest.NewRequest("POST", "/composition", bytes.NewReader(testPNG(t, 120, 90))), &res); code != http.StatusOK {
+		t.Fatalf("Status code was incorrect, got: %v, want: %v.", code, http.StatusOK)
+	}
+	if len(res.Boxes) != 5 {
+		t.Errorf("Boxes was incorrect, got: %v, want: %v.", len(res.Boxes), 5)
+	}
+	if last := res.Boxes[len(res.Boxes)-1]; last.Rect != image.Rect(0, 0, 120, 90) {
+		t.Errorf("Rect was incorrect, got: %v, want: %v.", last.Rect, image.Rect(0, 0, 120, 90))
+	}
+
+	res = CompositionResponse{}
+	do(t, s, httptest.NewRequest("POST", "/composition?grid=3x2", bytes.NewReader(testPNG(t, 120, 90))), &res)
+	if res.Grid.String() != "3x2" || len(res.Boxes) != 7 {
+		t.Errorf("Grid was incorrect, got: %v with %v boxes, want: %v with %v boxes.", res.Grid, len(res.Boxes), "3x2", 7)
+	}
+}
+
+func TestBlur(t *testing.T) {
+	s := NewServer(Config{})
+	var res imagecolor.BlurReport
+	if code := do(t, s, httptest.NewRequest("POST", "/blur?measure=tenengrad", bytes.NewReader(testPNG(t, 120, 90))), &res); code != http.StatusOK {
+		t.Fatalf("Status code was incorrect, got: %v, want: %v.", code, http.StatusOK)
+	}
+	// Three flat bands only have two edges
+	if res.Measure != imagecolor.Tenengrad || !res.Blurry || len(res.Regions) != 9 {
+		t.Errorf("Blur was incorrect, got: %v blurry %v with %v regions, want: %v blurry with %v regions.", res.Measure, res.Blurry, len(res.Regions), imagecolor.Tenengrad, 9)
+	}
+	if code := do(t, s, httptest.NewRequest("POST", "/blur", bytes.NewReader(testPNG(t, 2, 2))), nil); code != http.StatusBadRequest {
+		t.Errorf("Status code of a 2x2 image was incorrect, got: %v, want: %v.", code, http.StatusBadRequest)
+	}
+}
+
+func TestPath(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "sub", "test.png"), testPNG(t, 30, 30), 0644); err != nil {
+		t.Fatal(err)
+	}
+	outside := filepath.Join(t.TempDir(), "outside.png")
+	if err := os.WriteFile(outside, testPNG(t, 30, 30), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "escape.png")); err != nil {
+		t.Skip(err)
+	}
+	if err := os.Symlink(filepath.Join(root, "sub", "test.png"), filepath.Join(root, "link.png")); err != nil {
+		t.Fatal(err)
+	}
+	s := NewServer(Config{Root: root})
+	tests := []struct {
+		target string
+		code   int
+	}{
+		{"/colors?path=sub/test.png", http.StatusOK},
+		{"/hash?path=/sub/test.png", http.StatusOK},
+		{"/colors?path=sub", http.StatusNotFound},
+		{"/colors?path=missing.png", http.StatusNotFound},
+		{"/colors?path=../" + filepath.Base(root) + "/sub/test.png", http.StatusNotFound},
+		{"/colors?path=link.png", http.StatusOK},
+		{"/colors?path=escape.png", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if code := do(t, s, httptest.NewRequest("GET", tt.target, nil), nil); code != tt.code {
+			t.Errorf("%v was incorrect, got: %v, want: %v.", tt.target, code, tt.code)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	data := testPNG(t, 40, 40)
+	tests := []struct {
+		name   string
+		cfg    Config
+		method string
+		target string
+		body   []byte
+		code   int
+	}{
+		{"unknown format", Config{}, "POST", "/colors", []byte("not an image"), http.StatusUnsupportedMediaType},
+		{"invalid image", Config{}, "POST", "/colors", data[:len(data)/2], http.StatusBadRequest},
+		{"too many bytes", Config{MaxBytes: 64}, "POST", "/colors", data, http.StatusRequestEntityTooLarge},
+		{"too many pixels", Config{MaxPixels: 1000}, "POST", "/colors", data, http.StatusRequestEntityTooLarge},
+		{"paths disabled", Config{}, "GET", "/colors?path=test.png", nil, http.StatusForbidden},
+		{"missing image", Config{}, "GET", "/colors", nil, http.StatusBadRequest},
+		{"invalid limit", Config{}, "POST", "/colors?limit=2", data, http.StatusBadRequest},
+		{"size 0", Config{}, "POST", "/colors?size=0", data, http.StatusBadRequest},
+		{"size above Config.Size", Config{Size: 64}, "POST", "/colors?size=65", data, http.StatusBadRequest},
+		{"unknown kind", Config{}, "POST", "/hash?kind=xhash", data, http.StatusBadRequest},
+		{"unknown grid", Config{}, "POST", "/composition?grid=fifths", data, http.StatusBadRequest},
+		{"oversized grid", Config{}, "POST", "/composition?grid=100000x100000", data, http.StatusBadRequest},
+		{"grid larger than the image", Config{}, "POST", "/composition?grid=64x64", data, http.StatusBadRequest},
+		{"unknown measure", Config{}, "POST", "/blur?measure=sharpness", data, http.StatusBadRequest},
+		{"invalid threshold", Config{}, "POST", "/blur?threshold=-1", data, http.StatusBadRequest},
+		{"small composition", Config{}, "POST", "/composition", testPNG(t, 4, 4), http.StatusBadRequest},
+		{"method", Config{}, "PUT", "/hash", data, http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		var res struct{ Error string }
+		code := do(t, NewServer(tt.cfg), httptest.NewRequest(tt.method, tt.target, bytes.NewReader(tt.body)), &res)
+		if code != tt.code || res.Error == "" {
+			t.Errorf("%s was incorrect, got: %v %q, want: %v.", tt.name, code, res.Error, tt.code)
+		}
+	}
+}
+
+func TestBusy(t *testing.T) {
+	s := NewServer(Config{Concurrency: 1})
+	s.sem <- struct{}{} // an analysis is running
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	r := httptest.NewRequest("POST", "/colors", bytes.NewReader(testPNG(t, 10, 10))).WithContext(ctx)
+	if code := do(t, s, r, nil); code != http.StatusServiceUnavailable {
+		t.Errorf("Status code was incorrect, got: %v, want: %v.", code, http.StatusServiceUnavailable)
+	}
+
+	<-s.sem
+	r = httptest.NewRequest("POST", "/colors", bytes.NewReader(testPNG(t, 10, 10)))
+	if code := do(t, s, r, nil); code != http.StatusOK {
+		t.Errorf("Status code was incorrect, got: %v, want: %v.", code, http.StatusOK)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	s := NewServer(Config{Concurrency: 1, Timeout: 20 * time.Millisecond})
+	s.sem <- struct{}{}
+	defer func() { <-s.sem }()
+	r := httptest.NewRequest("POST", "/colors", bytes.NewReader(testPNG(t, 10, 10)))
+	if code := do(t, s, r, nil); code != http.StatusServiceUnavailable {
+		t.Errorf("Status code was incorrect, got: %v, want: %v.", code, http.StatusServiceUnavailable)
+	}
+}
+
+// slowReader - Reader that waits for delay before its first read
+type slowReader struct {
+	delay time.Duration
+	r     io.Reader
+}
+
+func (sr *slowReader) Read(p []byte) (int, error) {
+	time.Sleep(sr.delay)
+	sr.delay = 0
+	return sr.r.Read(p)
+}
+
+func TestTimeoutReleasesSlot(t *testing.T) {
+	s := NewServer(Config{Concurrency: 1, Timeout: 20 * time.Millisecond})
+	var calls int32
+	s.mux.Handle("/count", s.analysis(func(r *http.Request, img image.Image) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		return nil, nil
+	}))
+
+	// The upload outlasts the timeout, the analysis after it must not run
+	r := httptest.NewRequest("POST", "/count", &slowReader{100 * time.Millisecond, bytes.NewReader(testPNG(t, 10, 10))})
+	if code := do(t, s, r, nil); code != http.StatusServiceUnavailable {
+		t.Errorf("Status code was incorrect, got: %v, want: %v.", code, http.StatusServiceUnavailable)
+	}
+	for deadline := time.Now().Add(time.Second); len(s.sem) != 0 && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := len(s.sem); n != 0 {
+		t.Errorf("Busy slots was incorrect, got: %v, want: %v.", n, 0)
+	}
+	if n := atomic.LoadInt32(&calls); n != 0 {
+		t.Errorf("Analyses of a timed out request was incorrect, got: %v, want: %v.", n, 0)
+	}
+
+	r = httptest.NewRequest("POST", "/count", bytes.NewReader(testPNG(t, 10, 10)))
+	if code := do(t, s, r, nil); code != http.StatusOK {
+		t.Errorf("Status code was incorrect, got: %v, want: %v.", code, http.StatusOK)
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("Analyses was incorrect, got: %v, want: %v.", n, 1)
+	}
+}
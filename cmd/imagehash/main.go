@@ -0,0 +1,282 @@
+// Command imagehash computes and compares perceptual image hashes.
+//
+//	imagehash compute [-format text|json] file|glob|dir...
+//	imagehash compare file1 file2
+//	imagehash dupes [-kind phash] [-threshold 10] [-format json|csv] file|glob|dir...
+//
+// Images are resized to the size expected by every hash kind of hash.Kinds.
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"runtime"
+	"sort"
+	"strconv"
+
+	"github.com/evanoberholster/imageColor/hash"
+	"github.com/evanoberholster/imageColor/internal/files"
+	"github.com/evanoberholster/imageColor/loader"
+)
+
+const usage = `Usage: imagehash <command> [flags] [args]
+
+Commands:
+  compute   print the hashes of every kind for files or directories
+  compare   print the distance between two images for every hash kind
+  dupes     group near-duplicate images
+
+Hash kinds are ahash, dhash and phash. The wavelet hash (whash) is not
+implemented and the extended Ext* hashes of the hash package are not computed.
+
+Run imagehash <command> -h for the flags of a command.
+`
+
+func main() {
+	log.SetFlags(0)
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	var err error
+	switch cmd, args := os.Args[1], os.Args[2:]; cmd {
+	case "compute":
+		err = compute(os.Stdout, args)
+	case "compare":
+		err = compare(os.Stdout, args)
+	case "dupes":
+		err = dupes(os.Stdout, args)
+	case "-h", "-help", "--help", "help":
+		fmt.Fprint(os.Stdout, usage)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", cmd, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// fileHashes - Hashes of a single image
+type fileHashes struct {
+	File   string
+	Hashes map[hash.Kind]*hash.ImageHash
+	Err    error
+}
+
+// MarshalJSON - {"file", "ahash", "dhash", "phash", "error"}
+func (fh fileHashes) MarshalJSON() ([]byte, error) {
+	m := map[string]string{"file": fh.File}
+	for kind, h := range fh.Hashes {
+		m[kind.String()] = h.ToString()
+	}
+	if fh.Err != nil {
+		m["error"] = fh.Err.Error()
+	}
+	return json.Marshal(m)
+}
+
+// hashFiles - Hash paths with a pool of workers. Results are in the order of paths.
+func hashFiles(paths []string, kinds []hash.Kind, workers int) []fileHashes {
+	res := make([]fileHashes, len(paths))
+	jobs := make(chan int)
+	done := make(chan struct{})
+	if workers < 1 {
+		workers = 1
+	}
+	for n := 0; n < workers; n++ {
+		go func() {
+			for i := range jobs {
+				res[i] = hashFile(paths[i], kinds)
+			}
+			done <- struct{}{}
+		}()
+	}
+	for i := range paths {
+		jobs <- i
+	}
+	close(jobs)
+	for n := 0; n < workers; n++ {
+		<-done
+	}
+	return res
+}
+
+// hashFile - Hashes of kinds for a single image
+func hashFile(path string, kinds []hash.Kind) fileHashes {
+	fh := fileHashes{File: path, Hashes: make(map[hash.Kind]*hash.ImageHash, len(kinds))}
+	img, _, err := loader.Open(path)
+	if err != nil {
+		fh.Err = err
+		return fh
+	}
+	for _, kind := range kinds {
+		if fh.Hashes[kind], err = hash.Hash(img, kind); err != nil {
+			fh.Err = fmt.Errorf("%s: %w", path, err)
+			return fh
+		}
+	}
+	return fh
+}
+
+// compute - imagehash compute
+func compute(w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("compute", flag.ExitOnError)
+	format := fs.String("format", "text", "output `format`: text or json (JSON Lines)")
+	workers := fs.Int("workers", runtime.NumCPU(), "number of images hashed concurrently")
+	fs.Parse(args)
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+	paths, err := files.Expand(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	enc := json.NewEncoder(w)
+	for _, fh := range hashFiles(paths, hash.Kinds, *workers) {
+		if fh.Err != nil {
+			failed++
+		}
+		if *format == "json" {
+			if err := enc.Encode(fh); err != nil {
+				return err
+			}
+			continue
+		}
+		if fh.Err != nil {
+			log.Print(fh.Err)
+			continue
+		}
+		fmt.Fprint(w, fh.File)
+		for _, kind := range hash.Kinds {
+			fmt.Fprintf(w, "\t%s", fh.Hashes[kind].ToString())
+		}
+		fmt.Fprintln(w)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d images could not be hashed", failed, len(paths))
+	}
+	return nil
+}
+
+// compare - imagehash compare
+func compare(w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("compare", flag.ExitOnError)
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		return fmt.Errorf("compare needs 2 images, got %d", fs.NArg())
+	}
+	hashes := hashFiles(fs.Args(), hash.Kinds, 2)
+	for _, fh := range hashes {
+		if fh.Err != nil {
+			return fh.Err
+		}
+	}
+	for _, kind := range hash.Kinds {
+		d, err := hashes[0].Hashes[kind].Distance(hashes[1].Hashes[kind])
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s\t%d\n", kind, d)
+	}
+	return nil
+}
+
+// dupes - imagehash dupes
+func dupes(w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("dupes", flag.ExitOnError)
+	kindName := fs.String("kind", "phash", "hash `kind`: ahash, dhash or phash")
+	threshold := fs.Int("threshold", 10, "maximum hamming `distance` between near-duplicates")
+	format := fs.String("format", "json", "output `format`: json or csv")
+	workers := fs.Int("workers", runtime.NumCPU(), "number of images hashed concurrently")
+	fs.Parse(args)
+	kind, err := hash.ParseKind(*kindName)
+	if err != nil {
+		return fmt.Errorf("unknown hash kind %q", *kindName)
+	}
+	if *format != "json" && *format != "csv" {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+	paths, err := files.Expand(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	var hashed []fileHashes
+	for _, fh := range hashFiles(paths, []hash.Kind{kind}, *workers) {
+		if fh.Err != nil {
+			log.Print(fh.Err)
+			continue
+		}
+		hashed = append(hashed, fh)
+	}
+	groups := groupDuplicates(hashed, kind, *threshold)
+
+	if *format == "json" {
+		type group struct {
+			Files []string `json:"files"`
+		}
+		out := make([]group, len(groups))
+		for i, g := range groups {
+			out[i].Files = g
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(out)
+	}
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"group", "file"})
+	for i, g := range groups {
+		for _, f := range g {
+			cw.Write([]string{strconv.Itoa(i + 1), f})
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// groupDuplicates - Groups of files connected by hashes within threshold of each other.
+// Groups with a single file are dropped; files are sorted within and across groups.
+func groupDuplicates(hashed []fileHashes, kind hash.Kind, threshold int) [][]string {
+	parent := make([]int, len(hashed))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	for i := range hashed {
+		for j := i + 1; j < len(hashed); j++ {
+			d, err := hashed[i].Hashes[kind].Distance(hashed[j].Hashes[kind])
+			if err == nil && d <= threshold {
+				parent[find(j)] = find(i)
+			}
+		}
+	}
+
+	members := make(map[int][]string)
+	for i, fh := range hashed {
+		root := find(i)
+		members[root] = append(members[root], fh.File)
+	}
+	var groups [][]string
+	for _, g := range members {
+		if len(g) > 1 {
+			sort.Strings(g)
+			groups = append(groups, g)
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i][0] < groups[j][0] })
+	return groups
+}
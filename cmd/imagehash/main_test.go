@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/evanoberholster/imageColor/hash"
+)
+
+// writePNG - Write a 64x48 gray PNG with the values of f to dir
+func writePNG(t *testing.T, dir, name string, f func(x, y int) uint8) string {
+	img := image.NewGray(image.Rect(0, 0, 64, 48))
+	for y := 0; y < 48; y++ {
+		for x := 0; x < 64; x++ {
+			img.SetGray(x, y, color.Gray{f(x, y)})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// testImages - A gradient, a slightly brighter near-duplicate of it and a checkerboard
+func testImages(t *testing.T) (dir, a, b, c string) {
+	dir = t.TempDir()
+	a = writePNG(t, dir, "a.png", func(x, y int) uint8 { return uint8(x * 200 / 64) })
+	b = writePNG(t, dir, "b.png", func(x, y int) uint8 { return uint8(x*200/64 + 4) })
+	c = writePNG(t, dir, "c.png", func(x, y int) uint8 { return uint8((x/8+y/8)%2) * 255 })
+	return dir, a, b, c
+}
+
+func TestGroupDuplicates(t *testing.T) {
+	hashed := func(hashes ...uint64) []fileHashes {
+		res := make([]fileHashes, len(hashes))
+		for i, h := range hashes {
+			res[i] = fileHashes{
+				File:   string(rune('a' + len(hashes) - 1 - i)), // files in reverse order
+				Hashes: map[hash.Kind]*hash.ImageHash{hash.PHash: hash.NewImageHash(h, hash.PHash)},
+			}
+		}
+		return res
+	}
+	tests := []struct {
+		name      string
+		hashed    []fileHashes
+		threshold int
+		groups    [][]string
+	}{
+		{"empty", nil, 10, nil},
+		{"no duplicates", hashed(0x0, 0xff), 2, nil},
+		{"pair", hashed(0x0, 0x3, 0xff00), 2, [][]string{{"b", "c"}}},
+		{"threshold 0", hashed(0x0, 0x0, 0x1), 0, [][]string{{"b", "c"}}},
+		{"chained", hashed(0x0, 0x3, 0xf), 2, [][]string{{"a", "b", "c"}}},
+		{"two groups", hashed(0x0, 0xff00, 0x1, 0xff01), 1, [][]string{{"a", "c"}, {"b", "d"}}},
+	}
+	for _, tt := range tests {
+		if groups := groupDuplicates(tt.hashed, hash.PHash, tt.threshold); !reflect.DeepEqual(groups, tt.groups) {
+			t.Errorf("%s groups was incorrect, got: %v, want: %v.", tt.name, groups, tt.groups)
+		}
+	}
+}
+
+func TestCompute(t *testing.T) {
+	dir, a, b, c := testImages(t)
+	paths := []string{a, b, c}
+
+	var text bytes.Buffer
+	if err := compute(&text, []string{"-workers", "2", dir}); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(text.String()), "\n")
+	if len(lines) != len(paths) {
+		t.Fatalf("Text lines was incorrect, got: %v, want: %v.", len(lines), len(paths))
+	}
+	for i, line := range lines {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 1+len(hash.Kinds) || fields[0] != paths[i] {
+			t.Errorf("Line %d was incorrect, got: %q, want: %v and %d hashes.", i, line, paths[i], len(hash.Kinds))
+			continue
+		}
+		for j, kind := range hash.Kinds {
+			if h, err := hash.ImageHashFromString(fields[j+1]); err != nil || h.GetKind() != kind {
+				t.Errorf("Hash %q was incorrect, got: %v, want: a %v hash.", fields[j+1], err, kind)
+			}
+		}
+	}
+
+	var jsonl bytes.Buffer
+	if err := compute(&jsonl, []string{"-format", "json", a, b}); err != nil {
+		t.Fatal(err)
+	}
+	scanner := bufio.NewScanner(&jsonl)
+	for i := 0; scanner.Scan(); i++ {
+		var rec map[string]string
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			t.Fatal(err)
+		}
+		if rec["file"] != paths[i] || rec["ahash"] == "" || rec["dhash"] == "" || rec["phash"] == "" {
+			t.Errorf("Record %d was incorrect, got: %v, want: %v with every hash kind.", i, rec, paths[i])
+		}
+	}
+
+	if err := compute(&jsonl, []string{"-format", "xml", dir}); err == nil {
+		t.Errorf("Error of an unknown format was incorrect, got: %v, want: an error.", err)
+	}
+	invalid := filepath.Join(t.TempDir(), "invalid.png")
+	if err := os.WriteFile(invalid, []byte("not an image"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := compute(&bytes.Buffer{}, []string{a, invalid}); err == nil || !strings.Contains(err.Error(), "1 of 2") {
+		t.Errorf("Error of an invalid file was incorrect, got: %v, want: %v.", err, "1 of 2 images could not be hashed")
+	}
+}
+
+func TestCompare(t *testing.T) {
+	_, a, _, c := testImages(t)
+	tests := []struct {
+		name string
+		b    string
+		zero bool
+	}{
+		{"same", a, true},
+		{"different", c, false},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		if err := compare(&out, []string{a, tt.b}); err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		if len(lines) != len(hash.Kinds) {
+			t.Fatalf("%s lines was incorrect, got: %q, want: %v lines.", tt.name, out.String(), len(hash.Kinds))
+		}
+		for i, line := range lines {
+			kind, distance, _ := strings.Cut(line, "\t")
+			if kind != hash.Kinds[i].String() || (distance == "0") != tt.zero {
+				t.Errorf("%s line was incorrect, got: %q, want: %v with zero distance %v.", tt.name, line, hash.Kinds[i], tt.zero)
+			}
+		}
+	}
+	if err := compare(&bytes.Buffer{}, []string{a}); err == nil {
+		t.Errorf("Error of a single image was incorrect, got: %v, want: an error.", err)
+	}
+}
+
+func TestDupes(t *testing.T) {
+	dir, a, b, _ := testImages(t)
+
+	var out bytes.Buffer
+	if err := dupes(&out, []string{"-threshold", "2", dir}); err != nil {
+		t.Fatal(err)
+	}
+	var groups []struct{ Files []string }
+	if err := json.Unmarshal(out.Bytes(), &groups); err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 1 || !reflect.DeepEqual(groups[0].Files, []string{a, b}) {
+		t.Errorf("JSON groups was incorrect, got: %v, want: %v.", groups, [][]string{{a, b}})
+	}
+
+	out.Reset()
+	if err := dupes(&out, []string{"-kind", "ahash", "-format", "csv", dir}); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&out).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{{"group", "file"}, {"1", a}, {"1", b}}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("CSV was incorrect, got: %v, want: %v.", records, want)
+	}
+
+	for _, args := range [][]string{{"-kind", "whash", dir}, {"-format", "xml", dir}} {
+		if err := dupes(&bytes.Buffer{}, args); err == nil {
+			t.Errorf("Error of %v was incorrect, got: %v, want: an error.", args, err)
+		}
+	}
+}
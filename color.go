@@ -38,34 +38,9 @@ func (c ColorHSL) Distance(c2 ColorHSL) float64 {
 	return math.Sqrt((hDistance * hDistance) + ((c[1] - c2[1]) * (c[1] - c2[1])) + ((c[2] - c2[2]) * (c[2] - c2[2])))
 }
 
-// ClosestMaterialColor -
-func closestMaterialColor(c ColorHSL) (colorName MaterialColor) {
-	// Check for black pixels
-	if c[lightValue] < 0.05 {
-		colorName = materialBlack
-		return
-	}
-	// Check for white pixels
-	if c[saturationValue] < 0.018 && c[lightValue] > 0.95 {
-		colorName = materialWhite
-		return
-	}
-	// Check for grey pixels
-	if c[saturationValue] == 0.0 && c[hueValue] == 0.0 && c[lightValue] > 0.05 && c[lightValue] < 0.95 {
-		colorName = materialGrey
-		return
-	}
-	minDist := 200.0
-	for _, series := range materialColorsSeries {
-		for mc, ch := range series {
-			dist := c.Distance(ch)
-			if dist < minDist {
-				minDist = dist
-				colorName = mc
-			}
-		}
-	}
-	return colorName
+// ClosestMaterialColor - Classify a color using the Palette of every Material color series
+func closestMaterialColor(c ColorHSL) MaterialColor {
+	return materialPalette.Classify(c)
 }
 
 // ProminentColors - Return Prominent Colors
@@ -222,20 +197,9 @@ func (ic *ImageColors) defineSize(width, height int) {
 // GetImageColors - Create ImageColors array from an image
 func GetImageColors(m image.Image) *ImageColors {
 	bounds := m.Bounds()
-	minX, minY := bounds.Min.X, bounds.Min.Y
-	width, height := bounds.Max.X-minX, bounds.Max.Y-minY
 	var ic ImageColors
-	ic = make([][]ColorHSL, width)
-	for x := 0; x < width; x++ {
-		ic[x] = make([]ColorHSL, height)
-		for y := 0; y < height; y++ {
-			cf := newColorful(m.At(x+minX, y+minY).RGBA())
-			//cf, _ := colorful.MakeColor(m.At(x+minX, y+minY))
-			//colorHSL := NewColorHSL(cf)
-			//ic.AddHSL(x, y, colorHSL)
-			ic.AddColor(x, y, cf)
-		}
-	}
+	ic.defineSize(bounds.Dx(), bounds.Dy())
+	fillHSL(ic, m)
 	return &ic
 }
 
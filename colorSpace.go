@@ -0,0 +1,217 @@
+package imagecolor
+
+import (
+	"image"
+	"math"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+// ColorSpace - RGB color space of an input image.
+// Defined by a tone response curve per channel and a matrix
+// from linear RGB to linear sRGB (D65).
+type ColorSpace struct {
+	Name   string
+	toSRGB [3][3]float64
+	trc    [3]toneCurve
+}
+
+// White points (XYZ)
+var (
+	whiteD65 = [3]float64{0.95047, 1.00000, 1.08883}
+	whiteD50 = [3]float64{0.96422, 1.00000, 0.82521}
+)
+
+// Predefined Color Spaces
+var (
+	// SRGB is the default color space assumed by GetImageColors.
+	SRGB = newColorSpace("sRGB", [3][2]float64{{0.64, 0.33}, {0.30, 0.60}, {0.15, 0.06}}, whiteD65, srgbCurve)
+	// DisplayP3 is used by photos from most recent phones.
+	DisplayP3 = newColorSpace("Display P3", [3][2]float64{{0.680, 0.320}, {0.265, 0.690}, {0.150, 0.060}}, whiteD65, srgbCurve)
+	// AdobeRGB is Adobe RGB (1998), used by camera exports.
+	AdobeRGB = newColorSpace("Adobe RGB (1998)", [3][2]float64{{0.64, 0.33}, {0.21, 0.71}, {0.15, 0.06}}, whiteD65, gammaCurve(563.0/256.0))
+	// ProPhoto is ProPhoto RGB (ROMM RGB).
+	ProPhoto = newColorSpace("ProPhoto RGB", [3][2]float64{{0.7347, 0.2653}, {0.1596, 0.8404}, {0.0366, 0.0001}}, whiteD50, prophotoCurve)
+)
+
+// Tone Response Curves
+var (
+	srgbCurve     = toneCurve{params: []float64{2.4, 1 / 1.055, 0.055 / 1.055, 1 / 12.92, 0.04045}}
+	prophotoCurve = toneCurve{params: []float64{1.8, 1, 0, 1.0 / 16.0, 1.0 / 32.0}}
+)
+
+// toneCurve - Tone response curve that decodes encoded values to linear light.
+// Either a parametric curve (ICC 'para' function types 0 to 4) or a sampled table.
+type toneCurve struct {
+	params []float64
+	table  []float64
+}
+
+func gammaCurve(g float64) toneCurve {
+	return toneCurve{params: []float64{g}}
+}
+
+// decode - Encoded value to linear value
+func (tc toneCurve) decode(v float64) float64 {
+	if len(tc.table) > 0 {
+		return tc.lookup(v)
+	}
+	p := tc.params
+	switch len(p) {
+	case 0:
+		return v
+	case 1: // Y = X^g
+		return math.Pow(math.Max(v, 0), p[0])
+	case 3: // Y = (aX+b)^g for X >= -b/a, else 0
+		if v >= -p[2]/p[1] {
+			return math.Pow(p[1]*v+p[2], p[0])
+		}
+		return 0
+	case 4: // Y = (aX+b)^g + c for X >= -b/a, else c
+		if v >= -p[2]/p[1] {
+			return math.Pow(p[1]*v+p[2], p[0]) + p[3]
+		}
+		return p[3]
+	case 5: // Y = (aX+b)^g for X >= d, else cX
+		if v >= p[4] {
+			return math.Pow(p[1]*v+p[2], p[0])
+		}
+		return p[3] * v
+	case 7: // Y = (aX+b)^g + e for X >= d, else cX + f
+		if v >= p[4] {
+			return math.Pow(p[1]*v+p[2], p[0]) + p[5]
+		}
+		return p[3]*v + p[6]
+	}
+	return v
+}
+
+// lookup - Linear interpolation in a sampled tone curve
+func (tc toneCurve) lookup(v float64) float64 {
+	if v <= 0 {
+		return tc.table[0]
+	}
+	if v >= 1 {
+		return tc.table[len(tc.table)-1]
+	}
+	pos := v * float64(len(tc.table)-1)
+	i := int(pos)
+	f := pos - float64(i)
+	return tc.table[i]*(1-f) + tc.table[i+1]*f
+}
+
+// newColorSpace - Create a ColorSpace from its primaries (xy chromaticities), white point and tone curve
+func newColorSpace(name string, primaries [3][2]float64, white [3]float64, trc toneCurve) *ColorSpace {
+	var m [3][3]float64
+	for i, p := range primaries {
+		m[0][i] = p[0] / p[1]
+		m[1][i] = 1
+		m[2][i] = (1 - p[0] - p[1]) / p[1]
+	}
+	s := mulMatVec(invertMat(m), white)
+	for r := 0; r < 3; r++ {
+		for c := 0; c < 3; c++ {
+			m[r][c] *= s[c]
+		}
+	}
+	return newColorSpaceFromXYZ(name, m, white, [3]toneCurve{trc, trc, trc})
+}
+
+// newColorSpaceFromXYZ - Create a ColorSpace from a linear RGB to XYZ matrix relative to white
+func newColorSpaceFromXYZ(name string, rgbToXYZ [3][3]float64, white [3]float64, trc [3]toneCurve) *ColorSpace {
+	m := mulMat(bradford(white, whiteD65), rgbToXYZ)
+	m = mulMat(xyzToLinearSRGB, m)
+	return &ColorSpace{Name: name, toSRGB: m, trc: trc}
+}
+
+// xyzToLinearSRGB - XYZ (D65) to linear sRGB
+var xyzToLinearSRGB = [3][3]float64{
+	{3.2409699419045226, -1.537383177570094, -0.4986107602930034},
+	{-0.9692436362808796, 1.8759675015077202, 0.04155505740717559},
+	{0.05563007969699366, -0.20397695888897652, 1.0569715142428786},
+}
+
+// bradfordMat - Bradford cone response matrix
+var bradfordMat = [3][3]float64{
+	{0.8951, 0.2664, -0.1614},
+	{-0.7502, 1.7135, 0.0367},
+	{0.0389, -0.0685, 1.0296},
+}
+
+// bradford - Chromatic adaptation matrix from white point src to white point dst
+func bradford(src, dst [3]float64) [3][3]float64 {
+	s := mulMatVec(bradfordMat, src)
+	d := mulMatVec(bradfordMat, dst)
+	var scale [3][3]float64
+	for i := 0; i < 3; i++ {
+		scale[i][i] = d[i] / s[i]
+	}
+	return mulMat(invertMat(bradfordMat), mulMat(scale, bradfordMat))
+}
+
+// Convert - Convert a color encoded in this ColorSpace to sRGB.
+// Colors outside of the sRGB gamut are clipped.
+func (cs *ColorSpace) Convert(c colorful.Color) colorful.Color {
+	if cs == nil || cs == SRGB {
+		return c
+	}
+	lin := [3]float64{cs.trc[0].decode(c.R), cs.trc[1].decode(c.G), cs.trc[2].decode(c.B)}
+	out := mulMatVec(cs.toSRGB, lin)
+	return colorful.LinearRgb(clamp01(out[0]), clamp01(out[1]), clamp01(out[2]))
+}
+
+// GetImageColorsInSpace - Create ImageColors array from an image encoded in ColorSpace cs.
+// Pixels are converted to sRGB before being analysed. A nil ColorSpace is treated as sRGB.
+func GetImageColorsInSpace(m image.Image, cs *ColorSpace) *ImageColors {
+	if cs == nil || cs == SRGB {
+		return GetImageColors(m)
+	}
+	bounds := m.Bounds()
+	minX, minY := bounds.Min.X, bounds.Min.Y
+	width, height := bounds.Max.X-minX, bounds.Max.Y-minY
+	pixel := pixelReader(m)
+	var ic ImageColors
+	ic.defineSize(width, height)
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			ic.AddColor(x, y, cs.Convert(pixel(x+minX, y+minY)))
+		}
+	}
+	return &ic
+}
+
+func clamp01(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
+
+func mulMat(a, b [3][3]float64) (res [3][3]float64) {
+	for r := 0; r < 3; r++ {
+		for c := 0; c < 3; c++ {
+			res[r][c] = a[r][0]*b[0][c] + a[r][1]*b[1][c] + a[r][2]*b[2][c]
+		}
+	}
+	return res
+}
+
+func mulMatVec(a [3][3]float64, v [3]float64) (res [3]float64) {
+	for r := 0; r < 3; r++ {
+		res[r] = a[r][0]*v[0] + a[r][1]*v[1] + a[r][2]*v[2]
+	}
+	return res
+}
+
+func invertMat(m [3][3]float64) (res [3][3]float64) {
+	det := m[0][0]*(m[1][1]*m[2][2]-m[1][2]*m[2][1]) -
+		m[0][1]*(m[1][0]*m[2][2]-m[1][2]*m[2][0]) +
+		m[0][2]*(m[1][0]*m[2][1]-m[1][1]*m[2][0])
+	res[0][0] = (m[1][1]*m[2][2] - m[1][2]*m[2][1]) / det
+	res[0][1] = (m[0][2]*m[2][1] - m[0][1]*m[2][2]) / det
+	res[0][2] = (m[0][1]*m[1][2] - m[0][2]*m[1][1]) / det
+	res[1][0] = (m[1][2]*m[2][0] - m[1][0]*m[2][2]) / det
+	res[1][1] = (m[0][0]*m[2][2] - m[0][2]*m[2][0]) / det
+	res[1][2] = (m[0][2]*m[1][0] - m[0][0]*m[1][2]) / det
+	res[2][0] = (m[1][0]*m[2][1] - m[1][1]*m[2][0]) / det
+	res[2][1] = (m[0][1]*m[2][0] - m[0][0]*m[2][1]) / det
+	res[2][2] = (m[0][0]*m[1][1] - m[0][1]*m[1][0]) / det
+	return res
+}
@@ -0,0 +1,160 @@
+package imagecolor
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"hash/crc32"
+	"math"
+	"testing"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+// testICCProfile - Build a minimal RGB matrix/TRC ICC profile with gamma TRCs
+func testICCProfile(rgbToXYZ [3][3]float64, gamma float64) []byte {
+	type tag struct {
+		sig  string
+		data []byte
+	}
+	fixed := func(f float64) []byte {
+		b := make([]byte, 4)
+		binary.BigEndian.PutUint32(b, uint32(int32(math.Round(f*65536))))
+		return b
+	}
+	var tags []tag
+	for c, sig := range []string{"rXYZ", "gXYZ", "bXYZ"} {
+		data := append([]byte("XYZ "), 0, 0, 0, 0)
+		for r := 0; r < 3; r++ {
+			data = append(data, fixed(rgbToXYZ[r][c])...)
+		}
+		tags = append(tags, tag{sig, data})
+	}
+	curv := append([]byte("curv"), 0, 0, 0, 0, 0, 0, 0, 1, byte(int(gamma*256)>>8), byte(int(gamma*256)), 0, 0)
+	for _, sig := range []string{"rTRC", "gTRC", "bTRC"} {
+		tags = append(tags, tag{sig, curv})
+	}
+
+	profile := make([]byte, 132+len(tags)*12)
+	copy(profile[16:], "RGB ")
+	copy(profile[20:], "XYZ ")
+	copy(profile[36:], "acsp")
+	binary.BigEndian.PutUint32(profile[128:], uint32(len(tags)))
+	for i, t := range tags {
+		entry := profile[132+i*12:]
+		copy(entry, t.sig)
+		binary.BigEndian.PutUint32(entry[4:], uint32(len(profile)))
+		binary.BigEndian.PutUint32(entry[8:], uint32(len(t.data)))
+		profile = append(profile, t.data...)
+	}
+	binary.BigEndian.PutUint32(profile, uint32(len(profile)))
+	return profile
+}
+
+func TestColorSpaceConvert(t *testing.T) {
+	white := colorful.Color{R: 1, G: 1, B: 1}
+	for _, cs := range []*ColorSpace{SRGB, DisplayP3, AdobeRGB, ProPhoto} {
+		res := cs.Convert(white)
+		if !res.AlmostEqualRgb(white) {
+			t.Errorf("%s white was incorrect, got: %v, want: %v.", cs.Name, res, white)
+		}
+	}
+
+	// A P3 mid green is more saturated than the same values in sRGB
+	green := colorful.Color{R: 0.2, G: 0.7, B: 0.2}
+	_, s1, _ := green.Hsl()
+	_, s2, _ := DisplayP3.Convert(green).Hsl()
+	if s2 <= s1 {
+		t.Errorf("Display P3 green saturation was incorrect, got: %.3f, want > %.3f.", s2, s1)
+	}
+}
+
+func TestColorSpaceFromICC(t *testing.T) {
+	// Adobe RGB primaries adapted to D50, as found in Adobe RGB (1998) profiles
+	adobeD50 := [3][3]float64{
+		{0.60974, 0.20528, 0.14919},
+		{0.31111, 0.62567, 0.06322},
+		{0.01947, 0.06087, 0.74457},
+	}
+	profile := testICCProfile(adobeD50, 2.19921875)
+
+	cs, err := ColorSpaceFromICC(profile)
+	if err != nil {
+		t.Fatalf("Error parsing ICC profile: %v", err)
+	}
+	for _, c := range []colorful.Color{{R: 1, G: 1, B: 1}, {R: 0.2, G: 0.7, B: 0.2}, {R: 0.9, G: 0.3, B: 0.1}} {
+		got, want := cs.Convert(c), AdobeRGB.Convert(c)
+		if got.DistanceRgb(want) > 0.01 {
+			t.Errorf("ICC conversion of %v was incorrect, got: %v, want: %v.", c, got, want)
+		}
+	}
+
+	// Embed in a JPEG APP2 segment split in two chunks
+	var jpeg bytes.Buffer
+	jpeg.Write([]byte{0xff, 0xd8})
+	half := len(profile) / 2
+	for i, part := range [][]byte{profile[half:], profile[:half]} {
+		seg := append([]byte("ICC_PROFILE\x00"), byte(2-i), 2)
+		seg = append(seg, part...)
+		jpeg.Write([]byte{0xff, 0xe2, byte((len(seg) + 2) >> 8), byte(len(seg) + 2)})
+		jpeg.Write(seg)
+	}
+	jpeg.Write([]byte{0xff, 0xda})
+
+	res, err := ExtractICCProfile(&jpeg)
+	if err != nil {
+		t.Fatalf("Error extracting ICC profile: %v", err)
+	}
+	if !bytes.Equal(res, profile) {
+		t.Errorf("Extracted ICC profile was incorrect")
+	}
+}
+
+// testPNGICC - PNG stream with an iCCP chunk holding the profile compressed at level
+func testPNGICC(profile []byte, level int) []byte {
+	var z bytes.Buffer
+	zw, _ := zlib.NewWriterLevel(&z, level)
+	zw.Write(profile)
+	zw.Close()
+	data := append([]byte("ICC\x00\x00"), z.Bytes()...)
+
+	var buf bytes.Buffer
+	buf.Write(pngSignature)
+	var hdr [8]byte
+	binary.BigEndian.PutUint32(hdr[:4], uint32(len(data)))
+	copy(hdr[4:], "iCCP")
+	buf.Write(hdr[:])
+	buf.Write(data)
+	binary.BigEndian.PutUint32(hdr[:4], crc32.ChecksumIEEE(append([]byte("iCCP"), data...)))
+	buf.Write(hdr[:4])
+	return buf.Bytes()
+}
+
+func TestExtractPNGICCProfile(t *testing.T) {
+	profile := testICCProfile([3][3]float64{{0.4361, 0.3851, 0.1431}, {0.2225, 0.7169, 0.0606}, {0.0139, 0.0971, 0.7141}}, 2.2)
+	res, err := ExtractICCProfile(bytes.NewReader(testPNGICC(profile, zlib.DefaultCompression)))
+	if err != nil {
+		t.Fatalf("Error extracting ICC profile: %v", err)
+	}
+	if !bytes.Equal(res, profile) {
+		t.Errorf("Extracted ICC profile was incorrect")
+	}
+
+	// A few KiB of zlib data inflating to more than 4 MiB
+	bomb := testPNGICC(make([]byte, maxICCProfileSize+1), zlib.DefaultCompression)
+	if _, err := ExtractICCProfile(bytes.NewReader(bomb)); err == nil || err.Error() != ErrorICCProfileTooLarge {
+		t.Errorf("Error was incorrect, got: %v, want: %v.", err, ErrorICCProfileTooLarge)
+	}
+	for _, level := range []int{zlib.DefaultCompression, zlib.NoCompression} {
+		if res, err := ExtractICCProfile(bytes.NewReader(testPNGICC(make([]byte, maxICCProfileSize), level))); err != nil || len(res) != maxICCProfileSize {
+			t.Errorf("Profile of 4 MiB at level %d was incorrect, got: %v bytes (%v), want: %v.", level, len(res), err, maxICCProfileSize)
+		}
+	}
+
+	// The chunk length is checked before the chunk is read
+	huge := append([]byte{}, pngSignature...)
+	huge = append(huge, 0x7f, 0xff, 0xff, 0xff, 'i', 'C', 'C', 'P', 'I', 'C', 'C', 0, 0)
+	if _, err := ExtractICCProfile(bytes.NewReader(huge)); err == nil || err.Error() != ErrorICCProfileTooLarge {
+		t.Errorf("Error was incorrect, got: %v, want: %v.", err, ErrorICCProfileTooLarge)
+	}
+}
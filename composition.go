@@ -3,8 +3,11 @@ package imagecolor
 // Experimental
 // Use with caution
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"image"
+	"io"
 	"math"
 	"sort"
 
@@ -12,6 +15,16 @@ import (
 	"github.com/anthonynsimon/bild/transform"
 )
 
+// Errors
+const (
+	ErrorCompositionSize = "Image is too small for composition analysis"
+)
+
+// Composition Defaults
+const (
+	minCompositionSize = 6 // smallest width and height with non empty thirds boxes
+)
+
 // ImageEdges -
 func (b Box) ImageEdges(img image.Image, radius float64) image.Image {
 	result := transform.Crop(img, b.Rect)
@@ -19,30 +32,65 @@ func (b Box) ImageEdges(img image.Image, radius float64) image.Image {
 	//return result
 }
 
-// CalcCompositionBoxes - Experimental
-func CalcCompositionBoxes(imgR image.Image) {
+// CompositionOptions - Options of CalcCompositionBoxes
+type CompositionOptions struct {
+	Grid CompositionGrid // boxes that are scored, the rule of thirds by default
+	// Debug receives the scores of every box as text, nothing is written when nil
+	Debug io.Writer
+}
+
+// CompositionBox - Focus scores of a box of a CompositionReport
+type CompositionBox struct {
+	Rect     image.Rectangle
+	Score    float64
+	Distance float64 // Score relative to the Score of the whole image
+	Focused  float64 // ratio of focused pixels
+	MeanL    float64 // lightness of the focused edges
+	StdL     float64
+	SkewL    float64
+}
+
+// CompositionReport - Result of CalcCompositionBoxes
+type CompositionReport struct {
+	Width  int              `json:"width"`
+	Height int              `json:"height"`
+	Grid   CompositionGrid  `json:"grid"`
+	Boxes  []CompositionBox `json:"boxes"` // boxes of the grid
+	Image  CompositionBox   `json:"image"` // whole image, scored from the pixels of the grid boxes
+}
+
+// CalcCompositionBoxes - Experimental.
+// Score the focus of the boxes of a composition grid of an image (500px wide for a
+// calibrated score) and of the whole image.
+func CalcCompositionBoxes(imgR image.Image, opts CompositionOptions) (CompositionReport, error) {
 	//imgR := resize.Resize(500, 0, img, resize.Lanczos3)
 	bounds := imgR.Bounds()
-	width, height := bounds.Max.X, bounds.Max.Y
-	ratio := float64(width) / float64(height)
-	// 4:3 500:375px image
-	fmt.Println(width, height, ratio)
+	width, height := bounds.Dx(), bounds.Dy()
+	if width < minCompositionSize || height < minCompositionSize {
+		return CompositionReport{}, errors.New(ErrorCompositionSize)
+	}
+	if !opts.Grid.valid() {
+		return CompositionReport{}, errors.New(ErrorCompositionGrid)
+	}
+	boxes := opts.Grid.Boxes(bounds)
+	if len(boxes) == 0 {
+		return CompositionReport{}, errors.New(ErrorCompositionSize)
+	}
+	for _, box := range boxes {
+		if box.Rect.Empty() {
+			return CompositionReport{}, errors.New(ErrorCompositionSize)
+		}
+	}
 
-	boxes := ThirdsBoxes(width, height)
 	for _, box := range boxes {
 		edges := box.ImageEdges(imgR, 1)
-		//filename := fmt.Sprintf("box%d.png", idx)
-		//if err := imgio.Save(filename, edges, imgio.PNGEncoder()); err != nil {
-		//	fmt.Println(err)
-		//	return
-		//}
 		box.FocusedPixels(edges)
 		box.MeanStd()
 		box.Skew()
 		box.FocusScore() // WIP
 	}
 
-	bigBox := NewBox(0, 0, width, height)
+	bigBox := NewBox(bounds.Min.X, bounds.Min.Y, bounds.Max.X, bounds.Max.Y)
 	var focused float64
 	for _, b := range boxes {
 		focused += b.Focused
@@ -53,17 +101,96 @@ func CalcCompositionBoxes(imgR image.Image) {
 	bigBox.MeanStd()
 	bigBox.Skew()
 	bigBox.FocusScore()
-	boxes = append(boxes, bigBox)
 
+	report := CompositionReport{Width: width, Height: height, Grid: opts.Grid, Image: bigBox.report(bigBox)}
 	for _, b := range boxes {
-		fmt.Printf("Score: %.3f\t", b.Score)
-		fmt.Printf("Dist: %.3f\t", b.Distance(bigBox))
-		fmt.Printf("Skew: %.3f\t", b.SkewL)
-		fmt.Printf("Focused\t %.2f \t", b.Focused)
-		fmt.Printf("Mean\t %.5f\t", b.MeanL)
-		fmt.Printf("Std\t %.5f\t\n", b.StdL)
+		report.Boxes = append(report.Boxes, b.report(bigBox))
 	}
+	if opts.Debug != nil {
+		if err := report.writeDebug(opts.Debug); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
 
+// report - CompositionBox of b, with the distance to the whole image box
+func (b *Box) report(whole *Box) CompositionBox {
+	return CompositionBox{
+		Rect:     b.Rect,
+		Score:    b.Score,
+		Distance: b.Distance(whole),
+		Focused:  b.Focused,
+		MeanL:    b.MeanL,
+		StdL:     b.StdL,
+		SkewL:    b.SkewL,
+	}
+}
+
+// writeDebug - Write the size of the image and a line of scores per box, the whole image last
+func (r CompositionReport) writeDebug(w io.Writer) error {
+	if _, err := fmt.Fprintln(w, r.Grid, r.Width, r.Height, float64(r.Width)/float64(r.Height)); err != nil {
+		return err
+	}
+	boxes := append([]CompositionBox(nil), r.Boxes...)
+	for _, b := range append(boxes, r.Image) {
+		if _, err := fmt.Fprintf(w, "Score: %.3f\tDist: %.3f\tSkew: %.3f\tFocused\t %.2f \tMean\t %.5f\tStd\t %.5f\t\n",
+			b.Score, b.Distance, b.SkewL, b.Focused, b.MeanL, b.StdL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compositionBoxJSON - JSON schema of CompositionBox
+type compositionBoxJSON struct {
+	Rect     [4]int  `json:"rect"` // x1, y1, x2, y2
+	Score    float64 `json:"score"`
+	Distance float64 `json:"distance"`
+	Focused  float64 `json:"focused"`
+	Mean     float64 `json:"mean"`
+	Std      float64 `json:"std"`
+	Skew     float64 `json:"skew"`
+}
+
+// MarshalJSON - Encode a CompositionBox. NaN and infinite scores (boxes without
+// focused pixels) are encoded as 0.
+func (b CompositionBox) MarshalJSON() ([]byte, error) {
+	return json.Marshal(compositionBoxJSON{
+		Rect:     [4]int{b.Rect.Min.X, b.Rect.Min.Y, b.Rect.Max.X, b.Rect.Max.Y},
+		Score:    finite(b.Score),
+		Distance: finite(b.Distance),
+		Focused:  finite(b.Focused),
+		Mean:     finite(b.MeanL),
+		Std:      finite(b.StdL),
+		Skew:     finite(b.SkewL),
+	})
+}
+
+// UnmarshalJSON - Decode a CompositionBox
+func (b *CompositionBox) UnmarshalJSON(data []byte) error {
+	var bj compositionBoxJSON
+	if err := json.Unmarshal(data, &bj); err != nil {
+		return err
+	}
+	*b = CompositionBox{
+		Rect:     image.Rect(bj.Rect[0], bj.Rect[1], bj.Rect[2], bj.Rect[3]),
+		Score:    bj.Score,
+		Distance: bj.Distance,
+		Focused:  bj.Focused,
+		MeanL:    bj.Mean,
+		StdL:     bj.Std,
+		SkewL:    bj.Skew,
+	}
+	return nil
+}
+
+// finite - f, or 0 when f is NaN or infinite (not representable in JSON)
+func finite(f float64) float64 {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0
+	}
+	return f
 }
 
 // Distance -
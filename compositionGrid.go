@@ -0,0 +1,209 @@
+package imagecolor
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"math"
+	"strings"
+)
+
+// Errors
+const (
+	ErrorCompositionGrid = "Unknown or invalid composition grid"
+)
+
+// GridKind - Composition guide of a CompositionGrid
+type GridKind uint8
+
+// Grid Kinds
+const (
+	// GridThirds boxes are centered on the intersections of the rule of thirds lines.
+	GridThirds GridKind = iota
+	// GridPhi boxes are centered on the intersections of the phi grid lines (0.382 and 0.618).
+	GridPhi
+	// GridGoldenSpiral boxes are centered on the eyes of the four orientations of the golden spiral.
+	GridGoldenSpiral
+	// GridDiagonal boxes are centered on the intersections of the 45 degree lines from the corners.
+	GridDiagonal
+	// GridCenter is a single box in the center of the image.
+	GridCenter
+	// GridNxM divides the image in Columns x Rows equal boxes.
+	GridNxM
+)
+
+// Grid Defaults
+const (
+	gridBoxSize = 1.0 / 3.0 // size of the boxes centered on guide points, relative to the image
+)
+
+// MaxGridSize - Largest number of Columns and Rows of a GridNxM
+const MaxGridSize = 64
+
+var (
+	phi        = (1 + math.Sqrt(5)) / 2
+	phiLine    = 1 / (phi * phi)   // 0.382, the first line of the phi grid
+	spiralLine = 1 / (phi*phi + 1) // 0.276, the golden spiral eye in a golden rectangle
+	gridNames  = [...]string{"thirds", "phi", "golden-spiral", "diagonal", "center"}
+)
+
+// CompositionGrid - Composition guide used by CalcCompositionBoxes.
+// The zero value is the rule of thirds.
+type CompositionGrid struct {
+	Kind    GridKind
+	Columns int // GridNxM only, 1 to MaxGridSize
+	Rows    int // GridNxM only, 1 to MaxGridSize
+}
+
+// NormRect - Rectangle in normalized coordinates: (0, 0) is the top left corner and (1, 1)
+// the bottom right corner of an image
+type NormRect struct {
+	X1, Y1, X2, Y2 float64
+}
+
+// Map - Rectangle of r in the image bounds rect
+func (r NormRect) Map(rect image.Rectangle) image.Rectangle {
+	w, h := float64(rect.Dx()), float64(rect.Dy())
+	return image.Rect(
+		rect.Min.X+int(math.Round(r.X1*w)), rect.Min.Y+int(math.Round(r.Y1*h)),
+		rect.Min.X+int(math.Round(r.X2*w)), rect.Min.Y+int(math.Round(r.Y2*h)),
+	)
+}
+
+// NewNxMGrid - Grid of columns x rows equal boxes
+func NewNxMGrid(columns, rows int) CompositionGrid {
+	return CompositionGrid{Kind: GridNxM, Columns: columns, Rows: rows}
+}
+
+// ParseGrid - Grid from its name: thirds, phi, golden-spiral, diagonal, center or
+// columns x rows such as 3x2 (up to MaxGridSize x MaxGridSize)
+func ParseGrid(s string) (CompositionGrid, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for i, name := range gridNames {
+		if s == name {
+			return CompositionGrid{Kind: GridKind(i)}, nil
+		}
+	}
+	var columns, rows int
+	if n, err := fmt.Sscanf(s, "%dx%d", &columns, &rows); err == nil && n == 2 && s == fmt.Sprintf("%dx%d", columns, rows) {
+		if g := NewNxMGrid(columns, rows); g.valid() {
+			return g, nil
+		}
+	}
+	return CompositionGrid{}, errors.New(ErrorCompositionGrid)
+}
+
+// String - Name of the grid, as parsed by ParseGrid
+func (g CompositionGrid) String() string {
+	if g.Kind == GridNxM {
+		return fmt.Sprintf("%dx%d", g.Columns, g.Rows)
+	}
+	if int(g.Kind) < len(gridNames) {
+		return gridNames[g.Kind]
+	}
+	return fmt.Sprintf("GridKind(%d)", g.Kind)
+}
+
+// MarshalText - Encode the grid as its name
+func (g CompositionGrid) MarshalText() ([]byte, error) {
+	if !g.valid() {
+		return nil, errors.New(ErrorCompositionGrid)
+	}
+	return []byte(g.String()), nil
+}
+
+// UnmarshalText - Decode a grid from its name
+func (g *CompositionGrid) UnmarshalText(text []byte) error {
+	grid, err := ParseGrid(string(text))
+	if err != nil {
+		return err
+	}
+	*g = grid
+	return nil
+}
+
+func (g CompositionGrid) valid() bool {
+	if g.Kind == GridNxM {
+		return g.Columns > 0 && g.Rows > 0 && g.Columns <= MaxGridSize && g.Rows <= MaxGridSize
+	}
+	return g.Kind < GridNxM
+}
+
+// Rects - Boxes of the grid in normalized coordinates, for an image of aspect ratio
+// width / height (only GridDiagonal depends on it). Nil for an invalid grid.
+func (g CompositionGrid) Rects(aspect float64) []NormRect {
+	if !g.valid() || !(aspect > 0) {
+		return nil
+	}
+	switch g.Kind {
+	case GridThirds:
+		return pointRects([][2]float64{{1.0 / 3, 1.0 / 3}, {2.0 / 3, 1.0 / 3}, {1.0 / 3, 2.0 / 3}, {2.0 / 3, 2.0 / 3}})
+	case GridPhi:
+		return pointRects([][2]float64{{phiLine, phiLine}, {1 - phiLine, phiLine}, {phiLine, 1 - phiLine}, {1 - phiLine, 1 - phiLine}})
+	case GridGoldenSpiral:
+		return pointRects([][2]float64{{spiralLine, spiralLine}, {1 - spiralLine, spiralLine}, {spiralLine, 1 - spiralLine}, {1 - spiralLine, 1 - spiralLine}})
+	case GridDiagonal:
+		// Lines from the top corners meet at (w/2, w/2), from the bottom corners at
+		// (w/2, h-w/2), from the left corners at (h/2, h/2) and from the right corners at (w-h/2, h/2)
+		var points [][2]float64
+		for _, p := range [][2]float64{{0.5, aspect / 2}, {0.5, 1 - aspect/2}, {1 / (2 * aspect), 0.5}, {1 - 1/(2*aspect), 0.5}} {
+			if p[0] < 0 || p[0] > 1 || p[1] < 0 || p[1] > 1 || containsPoint(points, p) {
+				continue
+			}
+			points = append(points, p)
+		}
+		return pointRects(points)
+	case GridCenter:
+		return pointRects([][2]float64{{0.5, 0.5}})
+	}
+	rects := make([]NormRect, 0, g.Columns*g.Rows)
+	for y := 0; y < g.Rows; y++ {
+		for x := 0; x < g.Columns; x++ {
+			rects = append(rects, NormRect{
+				float64(x) / float64(g.Columns), float64(y) / float64(g.Rows),
+				float64(x+1) / float64(g.Columns), float64(y+1) / float64(g.Rows),
+			})
+		}
+	}
+	return rects
+}
+
+// fits - Whether every box of the grid is at least a pixel in the image bounds rect
+func (g CompositionGrid) fits(rect image.Rectangle) bool {
+	if g.Kind == GridNxM {
+		return g.Columns <= rect.Dx() && g.Rows <= rect.Dy()
+	}
+	return !rect.Empty()
+}
+
+// Boxes - Boxes of the grid in the image bounds rect.
+// Nil for an invalid grid, or when the image has fewer pixels than the grid has boxes.
+func (g CompositionGrid) Boxes(rect image.Rectangle) (boxes []*Box) {
+	if !g.valid() || !g.fits(rect) {
+		return nil
+	}
+	for _, r := range g.Rects(float64(rect.Dx()) / float64(rect.Dy())) {
+		boxes = append(boxes, &Box{Rect: r.Map(rect)})
+	}
+	return boxes
+}
+
+// pointRects - Boxes of gridBoxSize centered on points, moved inside the image
+func pointRects(points [][2]float64) []NormRect {
+	rects := make([]NormRect, len(points))
+	for i, p := range points {
+		x := math.Min(math.Max(p[0]-gridBoxSize/2, 0), 1-gridBoxSize)
+		y := math.Min(math.Max(p[1]-gridBoxSize/2, 0), 1-gridBoxSize)
+		rects[i] = NormRect{x, y, x + gridBoxSize, y + gridBoxSize}
+	}
+	return rects
+}
+
+func containsPoint(points [][2]float64, p [2]float64) bool {
+	for _, q := range points {
+		if math.Abs(p[0]-q[0]) < 1e-9 && math.Abs(p[1]-q[1]) < 1e-9 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,204 @@
+package imagecolor
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/color"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testCompositionImage - Gray image with a checkerboard in its top left quarter
+func testCompositionImage(rect image.Rectangle) image.Image {
+	m := image.NewGray(rect)
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			v := uint8(128)
+			if x-rect.Min.X < rect.Dx()/2 && y-rect.Min.Y < rect.Dy()/2 && (x/4+y/4)%2 == 0 {
+				v = 255
+			}
+			m.SetGray(x, y, color.Gray{v})
+		}
+	}
+	return m
+}
+
+func TestCalcCompositionBoxes(t *testing.T) {
+	var debug bytes.Buffer
+	report, err := CalcCompositionBoxes(testCompositionImage(image.Rect(0, 0, 120, 90)), CompositionOptions{Debug: &debug})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Boxes) != 4 || report.Width != 120 || report.Height != 90 {
+		t.Fatalf("Report was incorrect, got: %d boxes %dx%d, want: %d boxes %dx%d.", len(report.Boxes), report.Width, report.Height, 4, 120, 90)
+	}
+	if report.Image.Rect != image.Rect(0, 0, 120, 90) || report.Image.Distance != 0 {
+		t.Errorf("Image box was incorrect, got: %v distance %v, want: %v distance 0.", report.Image.Rect, report.Image.Distance, image.Rect(0, 0, 120, 90))
+	}
+	if lines := strings.Count(debug.String(), "\n"); lines != 6 {
+		t.Errorf("Debug lines was incorrect, got: %v, want: %v.", lines, 6)
+	}
+
+	// Bounds that do not start at the origin give the same scores
+	offset, err := CalcCompositionBoxes(testCompositionImage(image.Rect(8, 4, 128, 94)), CompositionOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, b := range offset.Boxes {
+		if b.Rect != report.Boxes[i].Rect.Add(image.Pt(8, 4)) || b.Focused != report.Boxes[i].Focused {
+			t.Errorf("Box %d was incorrect, got: %v %v, want: %v %v.", i, b.Rect, b.Focused, report.Boxes[i].Rect.Add(image.Pt(8, 4)), report.Boxes[i].Focused)
+		}
+	}
+
+	if _, err := CalcCompositionBoxes(image.NewGray(image.Rect(0, 0, 5, 100)), CompositionOptions{}); err == nil || err.Error() != ErrorCompositionSize {
+		t.Errorf("Error was incorrect, got: %v, want: %v.", err, ErrorCompositionSize)
+	}
+}
+
+func TestCompositionBoxesFinite(t *testing.T) {
+	checkerboard := image.NewGray(image.Rect(0, 0, 120, 90))
+	flat := image.NewGray(image.Rect(0, 0, 120, 90))
+	for y := 0; y < 90; y++ {
+		for x := 0; x < 120; x++ {
+			flat.SetGray(x, y, color.Gray{128})
+			if (x/4+y/4)%2 == 0 {
+				checkerboard.SetGray(x, y, color.Gray{255})
+			}
+		}
+	}
+	for name, m := range map[string]image.Image{"checkerboard": checkerboard, "flat": flat} {
+		report, err := CalcCompositionBoxes(m, CompositionOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, b := range append(report.Boxes, report.Image) {
+			for _, v := range []float64{b.Score, b.Distance, b.Focused, b.MeanL, b.StdL, b.SkewL} {
+				if math.IsNaN(v) || math.IsInf(v, 0) {
+					t.Errorf("%s box %d was incorrect, got: %+v, want: finite values.", name, i, b)
+					break
+				}
+			}
+		}
+		if report.Image.Distance != 0 {
+			t.Errorf("%s image distance was incorrect, got: %v, want: %v.", name, report.Image.Distance, 0)
+		}
+	}
+
+	// Boxes without values or without variance
+	tests := []struct {
+		values                 []float64
+		mean, std, skew, score float64
+	}{
+		{nil, 0, 0, 0, 0},
+		{[]float64{0.5}, 0.5, 0, 0, 0},
+		{[]float64{0.5, 0.5, 0.5}, 0.5, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		b := Box{values: tt.values, Focused: 1}
+		b.MeanStd()
+		b.Skew()
+		b.FocusScore()
+		if b.MeanL != tt.mean || b.StdL != tt.std || b.SkewL != tt.skew || b.Score != tt.score {
+			t.Errorf("Box of %v was incorrect, got: %v %v %v %v, want: %v %v %v %v.", tt.values, b.MeanL, b.StdL, b.SkewL, b.Score, tt.mean, tt.std, tt.skew, tt.score)
+		}
+	}
+}
+
+func TestCompositionReportEncoding(t *testing.T) {
+	report := CompositionReport{
+		Width:  120,
+		Height: 90,
+		Boxes:  []CompositionBox{{Rect: image.Rect(20, 15, 60, 45), Score: 1.5, Distance: -0.25, Focused: 0.3, MeanL: 0.4, StdL: 0.1, SkewL: 2}},
+		Image:  CompositionBox{Rect: image.Rect(0, 0, 120, 90), Score: 1.75, Focused: 0.2, MeanL: 0.35, StdL: 0.12, SkewL: 1.5},
+	}
+	b, err := json.Marshal(report)
+	if err != nil {
+		t.Fatalf("Error encoding JSON: %v", err)
+	}
+	var decoded CompositionReport
+	if err = json.Unmarshal(b, &decoded); err != nil {
+		t.Fatalf("Error decoding JSON: %v", err)
+	}
+	if !reflect.DeepEqual(report, decoded) {
+		t.Errorf("JSON round trip was incorrect, got: %v, want: %v.", decoded, report)
+	}
+
+	// Boxes without focused pixels have NaN scores
+	report.Boxes[0].Score = math.NaN()
+	if _, err := json.Marshal(report); err != nil {
+		t.Errorf("Error encoding NaN was incorrect, got: %v, want: %v.", err, nil)
+	}
+}
+
+func TestCompositionGrid(t *testing.T) {
+	rect := image.Rect(10, 20, 310, 220)
+	tests := []struct {
+		grid  string
+		boxes int
+		first image.Rectangle
+	}{
+		{"thirds", 4, image.Rect(60, 53, 160, 120)},
+		{"phi", 4, image.Rect(75, 63, 175, 130)},
+		{"golden-spiral", 4, image.Rect(43, 42, 143, 109)},
+		{"diagonal", 4, image.Rect(110, 137, 210, 203)}, // lines from the top corners meet at (0.5, 0.75)
+		{"center", 1, image.Rect(110, 87, 210, 153)},
+		{"3x2", 6, image.Rect(10, 20, 110, 120)},
+	}
+	for _, tt := range tests {
+		grid, err := ParseGrid(tt.grid)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.grid, err)
+		}
+		if grid.String() != tt.grid {
+			t.Errorf("String was incorrect, got: %v, want: %v.", grid, tt.grid)
+		}
+		boxes := grid.Boxes(rect)
+		if len(boxes) != tt.boxes || boxes[0].Rect != tt.first {
+			t.Errorf("%s boxes was incorrect, got: %d %v, want: %d %v.", tt.grid, len(boxes), boxes[0].Rect, tt.boxes, tt.first)
+		}
+		for _, b := range boxes {
+			if !b.Rect.In(rect) {
+				t.Errorf("%s box was incorrect, got: %v, want: inside %v.", tt.grid, b.Rect, rect)
+			}
+		}
+	}
+
+	if boxes := NewNxMGrid(100000, 100000).Boxes(rect); boxes != nil {
+		t.Errorf("Oversized grid was incorrect, got: %v boxes, want: %v.", len(boxes), 0)
+	}
+	if boxes := NewNxMGrid(4, 4).Boxes(image.Rect(0, 0, 3, 10)); boxes != nil {
+		t.Errorf("Grid larger than the image was incorrect, got: %v boxes, want: %v.", len(boxes), 0)
+	}
+
+	// The diagonals of a square meet in the center
+	if rects := (CompositionGrid{Kind: GridDiagonal}).Rects(1); len(rects) != 1 {
+		t.Errorf("Square diagonal was incorrect, got: %v, want: %v.", rects, "1 box")
+	}
+	for _, s := range []string{"fifths", "0x2", "3x", "3x2x1", "", "65x2", "100000x100000", "3037000500x3037000500"} {
+		if _, err := ParseGrid(s); err == nil || err.Error() != ErrorCompositionGrid {
+			t.Errorf("ParseGrid(%q) was incorrect, got: %v, want: %v.", s, err, ErrorCompositionGrid)
+		}
+	}
+	if _, err := CalcCompositionBoxes(image.NewGray(image.Rect(0, 0, 60, 60)), CompositionOptions{Grid: NewNxMGrid(0, 2)}); err == nil || err.Error() != ErrorCompositionGrid {
+		t.Errorf("Error was incorrect, got: %v, want: %v.", err, ErrorCompositionGrid)
+	}
+	if _, err := CalcCompositionBoxes(image.NewGray(image.Rect(0, 0, 60, 60)), CompositionOptions{Grid: NewNxMGrid(MaxGridSize, 2)}); err == nil || err.Error() != ErrorCompositionSize {
+		t.Errorf("Error was incorrect, got: %v, want: %v.", err, ErrorCompositionSize)
+	}
+
+	report, err := CalcCompositionBoxes(testCompositionImage(image.Rect(0, 0, 120, 90)), CompositionOptions{Grid: CompositionGrid{Kind: GridPhi}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(report)
+	if err != nil {
+		t.Fatalf("Error encoding JSON: %v", err)
+	}
+	var decoded CompositionReport
+	if err = json.Unmarshal(b, &decoded); err != nil || decoded.Grid != report.Grid || len(decoded.Boxes) != 4 {
+		t.Errorf("JSON round trip was incorrect, got: %v %v, want: %v.", decoded.Grid, err, report.Grid)
+	}
+}
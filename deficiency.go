@@ -0,0 +1,136 @@
+package imagecolor
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+// Deficiency - Color vision deficiency
+type Deficiency uint8
+
+// Color Vision Deficiencies
+const (
+	Protanopia Deficiency = iota
+	Deuteranopia
+	Tritanopia
+)
+
+// Deficiencies - All simulated color vision deficiencies
+var Deficiencies = []Deficiency{Protanopia, Deuteranopia, Tritanopia}
+
+// String - format Deficiency as a String
+func (d Deficiency) String() string {
+	switch d {
+	case Protanopia:
+		return "Protanopia"
+	case Deuteranopia:
+		return "Deuteranopia"
+	case Tritanopia:
+		return "Tritanopia"
+	}
+	return "Unknown"
+}
+
+// deficiencyMatrices - Simulation matrices in linear RGB for severity 1.0
+// From: Machado, Oliveira and Fernandes (2009), A Physiologically-based Model
+// for Simulation of Color Vision Deficiency.
+var deficiencyMatrices = map[Deficiency][3][3]float64{
+	Protanopia: {
+		{0.152286, 1.052583, -0.204868},
+		{0.114503, 0.786281, 0.099216},
+		{-0.003882, -0.048116, 1.051998},
+	},
+	Deuteranopia: {
+		{0.367322, 0.860646, -0.227968},
+		{0.280085, 0.672501, 0.047413},
+		{-0.011820, 0.042940, 0.968881},
+	},
+	Tritanopia: {
+		{1.255528, -0.076749, -0.178779},
+		{-0.078411, 0.930809, 0.147602},
+		{0.004733, 0.691367, 0.303900},
+	},
+}
+
+// deficiencyMatrix - Simulation matrix for a severity in [0, 1].
+// Partial severities interpolate between the identity and the full matrix.
+func deficiencyMatrix(d Deficiency, severity float64) [3][3]float64 {
+	m := deficiencyMatrices[d]
+	severity = clamp01(severity)
+	for r := 0; r < 3; r++ {
+		for c := 0; c < 3; c++ {
+			identity := 0.0
+			if r == c {
+				identity = 1
+			}
+			m[r][c] = identity*(1-severity) + m[r][c]*severity
+		}
+	}
+	return m
+}
+
+// SimulateDeficiency - Color as seen with a color vision deficiency of the given severity [0, 1]
+func SimulateDeficiency(c colorful.Color, d Deficiency, severity float64) colorful.Color {
+	return simulate(c, deficiencyMatrix(d, severity))
+}
+
+func simulate(c colorful.Color, m [3][3]float64) colorful.Color {
+	r, g, b := c.LinearRgb()
+	out := mulMatVec(m, [3]float64{r, g, b})
+	return colorful.LinearRgb(clamp01(out[0]), clamp01(out[1]), clamp01(out[2]))
+}
+
+// SimulateDeficiencyImage - Image as seen with a color vision deficiency of the given severity [0, 1]
+func SimulateDeficiencyImage(m image.Image, d Deficiency, severity float64) *image.NRGBA {
+	mat := deficiencyMatrix(d, severity)
+	return mapColors(m, func(c colorful.Color) colorful.Color {
+		return simulate(c, mat)
+	})
+}
+
+// ConfusablePair - Pair of palette colors that become indistinguishable with a Deficiency
+type ConfusablePair struct {
+	A, B       int // indexes in the palette
+	Deficiency Deficiency
+	Distance   float64 // CIEDE2000 distance between the simulated colors
+}
+
+// ConfusableColors - Pairs of palette colors whose perceptual distance (CIEDE2000, 0-100 scale)
+// falls below threshold for any color vision deficiency.
+// Pairs that are already indistinguishable with normal vision are not reported.
+func ConfusableColors(palette color.Palette, threshold float64) (pairs []ConfusablePair) {
+	colors := make([]colorful.Color, len(palette))
+	for i, p := range palette {
+		colors[i], _ = straightColor(p)
+	}
+	for _, d := range Deficiencies {
+		mat := deficiencyMatrix(d, 1)
+		simulated := make([]colorful.Color, len(colors))
+		for i, c := range colors {
+			simulated[i] = simulate(c, mat)
+		}
+		for i := 0; i < len(colors); i++ {
+			for j := i + 1; j < len(colors); j++ {
+				if colors[i].DistanceCIEDE2000(colors[j])*100 < threshold {
+					continue
+				}
+				if dist := simulated[i].DistanceCIEDE2000(simulated[j]) * 100; dist < threshold {
+					pairs = append(pairs, ConfusablePair{A: i, B: j, Deficiency: d, Distance: dist})
+				}
+			}
+		}
+	}
+	return pairs
+}
+
+// Confusable - Pairs of prominent colors (indexes in pc.Colors) that become
+// indistinguishable for any color vision deficiency. See ConfusableColors.
+func (pc ProminentColors) Confusable(threshold float64) []ConfusablePair {
+	palette := make(color.Palette, len(pc.Colors))
+	for i, p := range pc.Colors {
+		palette[i] = p.Color.Colorful().Clamped()
+	}
+	return ConfusableColors(palette, threshold)
+}
@@ -0,0 +1,90 @@
+package imagecolor
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+func TestSimulateDeficiency(t *testing.T) {
+	// Machado protanopia matrix times the linear color (0.2, 0.5, 0.8)
+	c := colorful.LinearRgb(0.2, 0.5, 0.8)
+	want := [3]float64{
+		0.152286*0.2 + 1.052583*0.5 - 0.204868*0.8,
+		0.114503*0.2 + 0.786281*0.5 + 0.099216*0.8,
+		-0.003882*0.2 - 0.048116*0.5 + 1.051998*0.8,
+	}
+	r, g, b := SimulateDeficiency(c, Protanopia, 1).LinearRgb()
+	for i, v := range [3]float64{r, g, b} {
+		if math.Abs(v-want[i]) > 1e-9 {
+			t.Errorf("Protanopia channel %d was incorrect, got: %v, want: %v.", i, v, want[i])
+		}
+	}
+
+	// Half severity is halfway between the color and the full simulation
+	r, _, _ = SimulateDeficiency(c, Protanopia, 0.5).LinearRgb()
+	if math.Abs(r-(0.2+want[0])/2) > 1e-9 {
+		t.Errorf("Half severity was incorrect, got: %v, want: %v.", r, (0.2+want[0])/2)
+	}
+
+	for _, d := range Deficiencies {
+		if s := SimulateDeficiency(c, d, 0); s.DistanceRgb(c) > 1e-9 {
+			t.Errorf("%v severity 0 was incorrect, got: %v, want: %v.", d, s, c)
+		}
+		// Rows sum to 1, so grays are unchanged
+		gray := colorful.Color{R: 0.5, G: 0.5, B: 0.5}
+		if s := SimulateDeficiency(gray, d, 1); s.DistanceRgb(gray) > 1e-4 {
+			t.Errorf("%v of gray was incorrect, got: %v, want: %v.", d, s, gray)
+		}
+	}
+
+	m := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	m.SetNRGBA(0, 0, color.NRGBA{0xf4, 0x43, 0x36, 0xff})
+	m.SetNRGBA(1, 0, color.NRGBA{0x4c, 0xaf, 0x50, 0x80})
+	sim := SimulateDeficiencyImage(m, Deuteranopia, 1)
+	for x := 0; x < 2; x++ {
+		src := m.NRGBAAt(x, 0)
+		want := nrgba(SimulateDeficiency(colorful.Color{R: float64(src.R) / 255, G: float64(src.G) / 255, B: float64(src.B) / 255}, Deuteranopia, 1), src.A)
+		if got := sim.NRGBAAt(x, 0); got != want {
+			t.Errorf("Image pixel %d was incorrect, got: %v, want: %v.", x, got, want)
+		}
+	}
+}
+
+func TestConfusableColors(t *testing.T) {
+	const threshold = 10
+	palette := MaterialSeriesPalette(500)
+	pairs := ConfusableColors(palette, threshold)
+	if len(pairs) == 0 {
+		t.Fatalf("ConfusableColors was incorrect, got: no pairs, want: confusable Material colors.")
+	}
+	for _, p := range pairs {
+		a, _ := straightColor(palette[p.A])
+		b, _ := straightColor(palette[p.B])
+		sa, sb := SimulateDeficiency(a, p.Deficiency, 1), SimulateDeficiency(b, p.Deficiency, 1)
+		if p.A >= p.B || a.DistanceCIEDE2000(b)*100 < threshold || math.Abs(sa.DistanceCIEDE2000(sb)*100-p.Distance) > 1e-9 || p.Distance >= threshold {
+			t.Errorf("Pair was incorrect, got: %+v.", p)
+		}
+	}
+
+	// Saturated blue and yellow stay distinguishable with every deficiency
+	blue, yellow := colorful.Color{R: 0.2, G: 0.2, B: 0.9}, colorful.Color{R: 0.9, G: 0.9, B: 0.2}
+	for _, p := range ConfusableColors(color.Palette{blue, yellow}, threshold) {
+		t.Errorf("Blue and yellow was incorrect, got: confusable for %v.", p.Deficiency)
+	}
+
+	// Identical colors are not reported
+	if pairs := ConfusableColors(color.Palette{blue, blue}, threshold); len(pairs) != 0 {
+		t.Errorf("Identical colors was incorrect, got: %v, want: no pairs.", pairs)
+	}
+
+	pc := ProminentColors{Colors: []ProminentColor{{Color: materialRed, W: 0.5}, {Color: materialGreen, W: 0.5}}}
+	red, _ := straightColor(materialRed.Colorful().Clamped())
+	green, _ := straightColor(materialGreen.Colorful().Clamped())
+	if got, want := len(pc.Confusable(threshold)), len(ConfusableColors(color.Palette{red, green}, threshold)); got != want {
+		t.Errorf("Confusable was incorrect, got: %v pairs, want: %v.", got, want)
+	}
+}
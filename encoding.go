@@ -0,0 +1,379 @@
+package imagecolor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Encoding Version
+// Incremented whenever the JSON, text or binary layout changes.
+const EncodingVersion = 1
+
+// Errors
+const (
+	ErrorUnknownColor     = "Unknown material color"
+	ErrorEncodingVersion  = "Unsupported encoding version"
+	ErrorEncodingTooShort = "Encoded data is too short"
+	ErrorEncodingText     = "Malformed text encoding"
+)
+
+// materialColorCodes - Stable wire codes for MaterialColor.
+// These never change when the iota order of MaterialColor changes.
+// Code 0 is reserved for unknown colors.
+var materialColorCodes = map[MaterialColor]uint8{
+	materialRed:        1,
+	materialPink:       2,
+	materialPurple:     3,
+	materialDeepPurple: 4,
+	materialIndigo:     5,
+	materialBlue:       6,
+	materialLightBlue:  7,
+	materialCyan:       8,
+	materialTeal:       9,
+	materialGreen:      10,
+	materialLightGreen: 11,
+	materialLime:       12,
+	materialYellow:     13,
+	materialAmber:      14,
+	materialOrange:     15,
+	materialDeepOrange: 16,
+	materialBrown:      17,
+	materialGrey:       18,
+	materialBlueGrey:   19,
+	materialWhite:      20,
+	materialBlack:      21,
+}
+
+// Code - Stable wire code of the MaterialColor, 0 if unknown
+func (mc MaterialColor) Code() uint8 {
+	return materialColorCodes[mc]
+}
+
+// MaterialColorFromCode - MaterialColor from its stable wire code
+func MaterialColorFromCode(code uint8) (MaterialColor, error) {
+	for mc, c := range materialColorCodes {
+		if c == code {
+			return mc, nil
+		}
+	}
+	return 0, errors.New(ErrorUnknownColor)
+}
+
+// ParseMaterialColor - MaterialColor from its name (case insensitive)
+func ParseMaterialColor(name string) (MaterialColor, error) {
+	for mc, n := range materialColorsName {
+		if strings.EqualFold(n, name) {
+			return mc, nil
+		}
+	}
+	return 0, errors.New(ErrorUnknownColor)
+}
+
+// MarshalText - Encode MaterialColor as its name
+func (mc MaterialColor) MarshalText() ([]byte, error) {
+	name, ok := materialColorsName[mc]
+	if !ok {
+		return nil, errors.New(ErrorUnknownColor)
+	}
+	return []byte(name), nil
+}
+
+// UnmarshalText - Decode MaterialColor from its name
+func (mc *MaterialColor) UnmarshalText(text []byte) (err error) {
+	*mc, err = ParseMaterialColor(string(text))
+	return err
+}
+
+// MarshalBinary - Encode MaterialColor as its 1 byte wire code
+func (mc MaterialColor) MarshalBinary() ([]byte, error) {
+	code := mc.Code()
+	if code == 0 {
+		return nil, errors.New(ErrorUnknownColor)
+	}
+	return []byte{code}, nil
+}
+
+// UnmarshalBinary - Decode MaterialColor from its 1 byte wire code
+func (mc *MaterialColor) UnmarshalBinary(data []byte) (err error) {
+	if len(data) < 1 {
+		return errors.New(ErrorEncodingTooShort)
+	}
+	*mc, err = MaterialColorFromCode(data[0])
+	return err
+}
+
+// prominentColorJSON - JSON schema of a ProminentColor
+type prominentColorJSON struct {
+	Color  MaterialColor `json:"color"`
+	Hex    string        `json:"hex"`
+	Weight float64       `json:"weight"`
+}
+
+// MarshalJSON - Encode ProminentColor as {"color":"Red","hex":"#f44336","weight":0.25}
+func (p ProminentColor) MarshalJSON() ([]byte, error) {
+	return json.Marshal(prominentColorJSON{Color: p.Color, Hex: p.Color.Hex(), Weight: p.W})
+}
+
+// UnmarshalJSON - Decode ProminentColor. The "hex" field is informative and ignored.
+func (p *ProminentColor) UnmarshalJSON(data []byte) error {
+	var pj prominentColorJSON
+	if err := json.Unmarshal(data, &pj); err != nil {
+		return err
+	}
+	p.Color, p.W = pj.Color, pj.Weight
+	return nil
+}
+
+// MarshalText - Encode ProminentColor as "Red:0.25"
+func (p ProminentColor) MarshalText() ([]byte, error) {
+	name, err := p.Color.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(string(name) + ":" + formatFloat(p.W)), nil
+}
+
+// UnmarshalText - Decode ProminentColor from "Red:0.25"
+func (p *ProminentColor) UnmarshalText(text []byte) (err error) {
+	parts := strings.SplitN(string(text), ":", 2)
+	if len(parts) != 2 {
+		return errors.New(ErrorEncodingText)
+	}
+	if err = p.Color.UnmarshalText([]byte(parts[0])); err != nil {
+		return err
+	}
+	p.W, err = strconv.ParseFloat(parts[1], 64)
+	return err
+}
+
+// MarshalBinary - Encode ProminentColor as wire code (1 byte) and weight (float64 big endian)
+func (p ProminentColor) MarshalBinary() ([]byte, error) {
+	code, err := p.Color.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 9)
+	buf[0] = code[0]
+	binary.BigEndian.PutUint64(buf[1:], math.Float64bits(p.W))
+	return buf, nil
+}
+
+// UnmarshalBinary - Decode ProminentColor from its 9 byte binary encoding
+func (p *ProminentColor) UnmarshalBinary(data []byte) error {
+	if len(data) < 9 {
+		return errors.New(ErrorEncodingTooShort)
+	}
+	if err := p.Color.UnmarshalBinary(data[:1]); err != nil {
+		return err
+	}
+	p.W = math.Float64frombits(binary.BigEndian.Uint64(data[1:9]))
+	return nil
+}
+
+// statJSON - JSON schema of a Mean and Standard deviation pair
+type statJSON struct {
+	Mean float64 `json:"mean"`
+	Std  float64 `json:"std"`
+}
+
+// prominentColorsJSON - JSON schema of ProminentColors
+type prominentColorsJSON struct {
+	Version      int              `json:"version"`
+	Colors       []ProminentColor `json:"colors"`
+	Hue          statJSON         `json:"hue"`
+	Saturation   statJSON         `json:"saturation"`
+	Lightness    statJSON         `json:"lightness"`
+	Colorfulness float64          `json:"colorfulness"`
+	Qlightness   float64          `json:"qlightness"`
+}
+
+// MarshalJSON - Encode ProminentColors using the versioned JSON schema (see README)
+func (pc ProminentColors) MarshalJSON() ([]byte, error) {
+	colors := pc.Colors
+	if colors == nil {
+		colors = []ProminentColor{}
+	}
+	return json.Marshal(prominentColorsJSON{
+		Version:      EncodingVersion,
+		Colors:       colors,
+		Hue:          statJSON{pc.Hue[0], pc.Hue[1]},
+		Saturation:   statJSON{pc.Saturation[0], pc.Saturation[1]},
+		Lightness:    statJSON{pc.Lightness[0], pc.Lightness[1]},
+		Colorfulness: pc.Colorfulness,
+		Qlightness:   pc.Qlightness,
+	})
+}
+
+// UnmarshalJSON - Decode ProminentColors from the versioned JSON schema
+func (pc *ProminentColors) UnmarshalJSON(data []byte) error {
+	var pj prominentColorsJSON
+	if err := json.Unmarshal(data, &pj); err != nil {
+		return err
+	}
+	if pj.Version != EncodingVersion {
+		return errors.New(ErrorEncodingVersion)
+	}
+	*pc = ProminentColors{
+		Colors:       pj.Colors,
+		Hue:          [2]float64{pj.Hue.Mean, pj.Hue.Std},
+		Saturation:   [2]float64{pj.Saturation.Mean, pj.Saturation.Std},
+		Lightness:    [2]float64{pj.Lightness.Mean, pj.Lightness.Std},
+		Colorfulness: pj.Colorfulness,
+		Qlightness:   pj.Qlightness,
+	}
+	return nil
+}
+
+// MarshalText - Encode ProminentColors as a single line:
+// v1;colors=Red:0.25,Blue:0.1;hue=m,s;saturation=m,s;lightness=m,s;colorfulness=c;qlightness=q
+func (pc ProminentColors) MarshalText() ([]byte, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "v%d;colors=", EncodingVersion)
+	for i, p := range pc.Colors {
+		text, err := p.MarshalText()
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(text)
+	}
+	fmt.Fprintf(&buf, ";hue=%s,%s", formatFloat(pc.Hue[0]), formatFloat(pc.Hue[1]))
+	fmt.Fprintf(&buf, ";saturation=%s,%s", formatFloat(pc.Saturation[0]), formatFloat(pc.Saturation[1]))
+	fmt.Fprintf(&buf, ";lightness=%s,%s", formatFloat(pc.Lightness[0]), formatFloat(pc.Lightness[1]))
+	fmt.Fprintf(&buf, ";colorfulness=%s", formatFloat(pc.Colorfulness))
+	fmt.Fprintf(&buf, ";qlightness=%s", formatFloat(pc.Qlightness))
+	return buf.Bytes(), nil
+}
+
+// UnmarshalText - Decode ProminentColors from its text encoding
+func (pc *ProminentColors) UnmarshalText(text []byte) error {
+	fields := strings.Split(string(text), ";")
+	if len(fields) == 0 || fields[0] != "v"+strconv.Itoa(EncodingVersion) {
+		return errors.New(ErrorEncodingVersion)
+	}
+	var res ProminentColors
+	for _, field := range fields[1:] {
+		kv := strings.SplitN(field, "=", 2)
+		if len(kv) != 2 {
+			return errors.New(ErrorEncodingText)
+		}
+		var err error
+		switch kv[0] {
+		case "colors":
+			if kv[1] == "" {
+				continue
+			}
+			for _, item := range strings.Split(kv[1], ",") {
+				var p ProminentColor
+				if err = p.UnmarshalText([]byte(item)); err != nil {
+					return err
+				}
+				res.Colors = append(res.Colors, p)
+			}
+		case "hue":
+			res.Hue, err = parseFloatPair(kv[1])
+		case "saturation":
+			res.Saturation, err = parseFloatPair(kv[1])
+		case "lightness":
+			res.Lightness, err = parseFloatPair(kv[1])
+		case "colorfulness":
+			res.Colorfulness, err = strconv.ParseFloat(kv[1], 64)
+		case "qlightness":
+			res.Qlightness, err = strconv.ParseFloat(kv[1], 64)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	*pc = res
+	return nil
+}
+
+// MarshalBinary - Encode ProminentColors.
+// Layout: version (1 byte), color count (uvarint), colors (9 bytes each),
+// then hue, saturation and lightness mean/std, colorfulness and qlightness (float64 big endian).
+func (pc ProminentColors) MarshalBinary() ([]byte, error) {
+	buf := make([]byte, 1, 1+binary.MaxVarintLen64+len(pc.Colors)*9+8*8)
+	buf[0] = EncodingVersion
+	buf = binary.AppendUvarint(buf, uint64(len(pc.Colors)))
+	for _, p := range pc.Colors {
+		b, err := p.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, b...)
+	}
+	for _, f := range pc.binaryFloats() {
+		buf = binary.BigEndian.AppendUint64(buf, math.Float64bits(*f))
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary - Decode ProminentColors from its binary encoding
+func (pc *ProminentColors) UnmarshalBinary(data []byte) error {
+	if len(data) < 1 {
+		return errors.New(ErrorEncodingTooShort)
+	}
+	if data[0] != EncodingVersion {
+		return errors.New(ErrorEncodingVersion)
+	}
+	n, l := binary.Uvarint(data[1:])
+	if l <= 0 {
+		return errors.New(ErrorEncodingTooShort)
+	}
+	data = data[1+l:]
+	// n is compared without multiplying it, which would overflow for huge counts
+	if len(data) < 8*8 || n > uint64(len(data)-8*8)/9 {
+		return errors.New(ErrorEncodingTooShort)
+	}
+	var res ProminentColors
+	if n > 0 {
+		res.Colors = make([]ProminentColor, n)
+	}
+	for i := range res.Colors {
+		if err := res.Colors[i].UnmarshalBinary(data[:9]); err != nil {
+			return err
+		}
+		data = data[9:]
+	}
+	for _, f := range res.binaryFloats() {
+		*f = math.Float64frombits(binary.BigEndian.Uint64(data[:8]))
+		data = data[8:]
+	}
+	*pc = res
+	return nil
+}
+
+// binaryFloats - Float fields of ProminentColors in binary encoding order
+func (pc *ProminentColors) binaryFloats() []*float64 {
+	return []*float64{
+		&pc.Hue[0], &pc.Hue[1],
+		&pc.Saturation[0], &pc.Saturation[1],
+		&pc.Lightness[0], &pc.Lightness[1],
+		&pc.Colorfulness, &pc.Qlightness,
+	}
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+func parseFloatPair(s string) (res [2]float64, err error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 2 {
+		return res, errors.New(ErrorEncodingText)
+	}
+	if res[0], err = strconv.ParseFloat(parts[0], 64); err != nil {
+		return res, err
+	}
+	res[1], err = strconv.ParseFloat(parts[1], 64)
+	return res, err
+}
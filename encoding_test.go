@@ -0,0 +1,123 @@
+package imagecolor
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func testProminentColors() ProminentColors {
+	return ProminentColors{
+		Colors: []ProminentColor{
+			{Color: materialRed, W: 0.25},
+			{Color: materialBlueGrey, W: 0.125},
+			{Color: materialBlack, W: 1.0 / 3.0},
+		},
+		Hue:          [2]float64{12.5, 3.25},
+		Saturation:   [2]float64{0.42, 0.21},
+		Lightness:    [2]float64{0.51, 0.18},
+		Colorfulness: 0.79,
+		Qlightness:   0.63,
+	}
+}
+
+func TestProminentColorsEncoding(t *testing.T) {
+	pc := testProminentColors()
+
+	b, err := json.Marshal(pc)
+	if err != nil {
+		t.Fatalf("Error encoding JSON: %v", err)
+	}
+	var pcJSON ProminentColors
+	if err = json.Unmarshal(b, &pcJSON); err != nil {
+		t.Fatalf("Error decoding JSON: %v", err)
+	}
+	if !reflect.DeepEqual(pc, pcJSON) {
+		t.Errorf("JSON round trip was incorrect, got: %v, want: %v.", pcJSON, pc)
+	}
+
+	b, err = pc.MarshalText()
+	if err != nil {
+		t.Fatalf("Error encoding text: %v", err)
+	}
+	var pcText ProminentColors
+	if err = pcText.UnmarshalText(b); err != nil {
+		t.Fatalf("Error decoding text %s: %v", b, err)
+	}
+	if !reflect.DeepEqual(pc, pcText) {
+		t.Errorf("Text round trip was incorrect, got: %v, want: %v.", pcText, pc)
+	}
+
+	b, err = pc.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Error encoding binary: %v", err)
+	}
+	var pcBinary ProminentColors
+	if err = pcBinary.UnmarshalBinary(b); err != nil {
+		t.Fatalf("Error decoding binary: %v", err)
+	}
+	if !reflect.DeepEqual(pc, pcBinary) {
+		t.Errorf("Binary round trip was incorrect, got: %v, want: %v.", pcBinary, pc)
+	}
+}
+
+func TestProminentColorsBinaryErrors(t *testing.T) {
+	b, err := testProminentColors().MarshalBinary()
+	if err != nil {
+		t.Fatalf("Error encoding binary: %v", err)
+	}
+	// A count of MaxUint64/9+1 colors overflows n*9
+	huge := binary.AppendUvarint([]byte{EncodingVersion}, math.MaxUint64/9+1)
+	huge = append(huge, make([]byte, 8*8)...)
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"empty", nil, ErrorEncodingTooShort},
+		{"version", []byte{EncodingVersion + 1}, ErrorEncodingVersion},
+		{"no count", []byte{EncodingVersion}, ErrorEncodingTooShort},
+		{"truncated", b[:len(b)-1], ErrorEncodingTooShort},
+		{"truncated statistics", b[:len(b)-8*8], ErrorEncodingTooShort},
+		{"huge count", huge, ErrorEncodingTooShort},
+		{"count past the end", append(binary.AppendUvarint([]byte{EncodingVersion}, 2), make([]byte, 8*8+9)...), ErrorEncodingTooShort},
+	}
+	for _, tt := range tests {
+		var pc ProminentColors
+		if err := pc.UnmarshalBinary(tt.data); err == nil || err.Error() != tt.want {
+			t.Errorf("%s was incorrect, got: %v, want: %v.", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestProminentColorJSON(t *testing.T) {
+	b, err := json.Marshal(ProminentColor{Color: materialRed, W: 0.5})
+	if err != nil {
+		t.Fatalf("Error encoding JSON: %v", err)
+	}
+	want := `{"color":"Red","hex":"#f44336","weight":0.5}`
+	if string(b) != want {
+		t.Errorf("ProminentColor JSON was incorrect, got: %s, want: %s.", b, want)
+	}
+}
+
+func TestMaterialColorCodes(t *testing.T) {
+	codes := make(map[uint8]bool)
+	for mc := range materialColorsName {
+		code := mc.Code()
+		if code == 0 || codes[code] {
+			t.Errorf("MaterialColor %v has invalid or duplicate code %d", mc, code)
+		}
+		codes[code] = true
+		res, err := MaterialColorFromCode(code)
+		if err != nil || res != mc {
+			t.Errorf("MaterialColorFromCode(%d) was incorrect, got: %v, want: %v.", code, res, mc)
+		}
+	}
+	var mc MaterialColor
+	if err := mc.UnmarshalText([]byte("Magenta")); err == nil {
+		t.Errorf("Expected error decoding unknown color")
+	}
+}
@@ -2,35 +2,18 @@ package main
 
 import (
 	"fmt"
-	"image"
-	"image/jpeg"
 	"log"
-	"os"
 	"time"
 
 	imagecolor "github.com/evanoberholster/imageColor"
+	"github.com/evanoberholster/imageColor/loader"
 	"github.com/nfnt/resize"
 )
 
-func loadImage(fileName string) (image.Image, error) {
-	file, err := os.Open(fileName)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	// decode jpeg into image.Image
-	img, err := jpeg.Decode(file)
-	if err != nil {
-		log.Fatal(err)
-	}
-	defer file.Close()
-	return img, err
-}
-
 func main() {
-	img, err := loadImage("../../test/img/10.jpg")
+	img, _, err := loader.Open("../../test/img/10.jpg")
 	if err != nil {
-		panic(err)
+		log.Fatal(err)
 	}
 	imgR := resize.Resize(256, 256, img, resize.Lanczos3)
 
@@ -51,6 +34,6 @@ func main() {
 	fmt.Println("Time Since Start", time.Since(start))
 
 	//start = time.Now()
-	//color.CalcCompositionBoxes(img)
+	//color.CalcCompositionBoxes(img, color.CompositionOptions{Debug: os.Stdout})
 	//fmt.Println(time.Since(start))
 }
@@ -0,0 +1,75 @@
+package hash
+
+import (
+	"errors"
+	"image"
+
+	"github.com/nfnt/resize"
+)
+
+// Errors
+const (
+	ErrorUnsupportedKind = "Hash kind is not supported"
+)
+
+// Kinds - Hash kinds computed by Hash. WHash has no implementation and the
+// Ext hashes (ExtAverageHash, ...) return an ExtImageHash, they are not included.
+var Kinds = []Kind{AHash, DHash, PHash}
+
+// String - Name of the Kind
+func (k Kind) String() string {
+	switch k {
+	case AHash:
+		return "ahash"
+	case PHash:
+		return "phash"
+	case DHash:
+		return "dhash"
+	case WHash:
+		return "whash"
+	}
+	return "unknown"
+}
+
+// ParseKind - Kind from its name ("ahash", "dhash", "phash")
+func ParseKind(s string) (Kind, error) {
+	for _, k := range Kinds {
+		if k.String() == s {
+			return k, nil
+		}
+	}
+	return Unknown, errors.New(ErrorUnsupportedKind)
+}
+
+// Size - Width and Height of the image expected by the hash function of the Kind
+func (k Kind) Size() (int, int) {
+	switch k {
+	case AHash:
+		return 8, 8
+	case DHash:
+		return 9, 8
+	case PHash:
+		return 64, 64
+	}
+	return 0, 0
+}
+
+// Hash - Compute a hash of kind for an image of any size.
+// The image is resized (bilinear) to the size expected by the hash function.
+func Hash(img image.Image, kind Kind) (*ImageHash, error) {
+	if img == nil {
+		return nil, errors.New(ErrorNoImage)
+	}
+	width, height := kind.Size()
+	if width == 0 {
+		return nil, errors.New(ErrorUnsupportedKind)
+	}
+	resized := resize.Resize(uint(width), uint(height), img, resize.Bilinear)
+	switch kind {
+	case AHash:
+		return AverageHash(resized)
+	case DHash:
+		return DifferenceHash(resized)
+	}
+	return PerceptionHash(resized)
+}
@@ -0,0 +1,29 @@
+package hash
+
+import "testing"
+
+func TestHash(t *testing.T) {
+	img, err := fetchTestImage("tests/test1.jpg")
+	if err != nil {
+		t.Errorf("Error loading file: %v", err)
+	}
+	for _, h := range []struct {
+		kind Kind
+		hash string
+	}{
+		{AHash, "a:1c3cfed8f9f9f970"},
+		{PHash, "p:c996472b68fc248f"},
+	} {
+		hash, err := Hash(img, h.kind)
+		if err != nil {
+			t.Errorf("Error calculating Hash: %v", err)
+			continue
+		}
+		if hash.ToString() != h.hash {
+			t.Errorf("Hash %v was incorrect, got: %v, want: %v.", h.kind, hash.ToString(), h.hash)
+		}
+	}
+	if _, err := Hash(img, WHash); err == nil {
+		t.Errorf("Hash of WHash was incorrect, got: %v, want: %v.", err, ErrorUnsupportedKind)
+	}
+}
@@ -12,7 +12,7 @@ import (
 	"image"
 	"math"
 
-	"github.com/evanoberholster/imagecolor/hash/transforms"
+	"github.com/evanoberholster/imageColor/hash/transforms"
 )
 
 // Errors
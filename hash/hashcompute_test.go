@@ -3,27 +3,15 @@ package hash
 import (
 	"fmt"
 	"image"
-	"image/jpeg"
-	"log"
-	"os"
+	"image/color"
 	"testing"
 
+	"github.com/evanoberholster/imageColor/loader"
 	"github.com/nfnt/resize"
 )
 
 func fetchTestImage(fileName string) (image.Image, error) {
-	// open "test.jpg"
-	file, err := os.Open(fileName)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	// decode jpeg into image.Image
-	img, err := jpeg.Decode(file)
-	if err != nil {
-		log.Fatal(err)
-	}
-	defer file.Close()
+	img, _, err := loader.Open(fileName)
 	return img, err
 }
 
@@ -75,6 +63,25 @@ func TestAverageHash(t *testing.T) {
 	}
 }
 
+func TestAverageHash16(t *testing.T) {
+	// Both values truncate to 4 at 8 bits per channel
+	img := image.NewGray16(image.Rect(0, 0, 8, 8))
+	for i := 0; i < 64; i++ {
+		v := uint16(1030)
+		if i%2 == 1 {
+			v = 1200
+		}
+		img.SetGray16(i%8, i/8, color.Gray16{Y: v})
+	}
+	hash, err := AverageHash(img)
+	if err != nil {
+		t.Errorf("Error calculating AverageHash: %v", err)
+	}
+	if hash.ToString() != "a:5555555555555555" {
+		t.Errorf("AverageHash of Gray16 was incorrect, got: %v, want: %v.", hash.ToString(), "a:5555555555555555")
+	}
+}
+
 func TestDifferenceHash(t *testing.T) {
 	images := []struct {
 		fileName string
@@ -4,10 +4,9 @@ import (
 	"bufio"
 	"bytes"
 	"fmt"
-	"image/jpeg"
-	"os"
 	"testing"
 
+	"github.com/evanoberholster/imageColor/loader"
 	"github.com/nfnt/resize"
 )
 
@@ -24,11 +23,9 @@ import (
 //}
 
 func TestDump(t *testing.T) {
-	file1, _ := os.Open("tests/test1.jpg")
-	defer file1.Close()
 	var b bytes.Buffer
 	foo := bufio.NewWriter(&b)
-	img1, _ := jpeg.Decode(file1)
+	img1, _, _ := loader.Open("tests/test1.jpg")
 	resized := resize.Resize(256, 256, img1, resize.Bilinear)
 	hash1, _ := ExtPerceptionHash(resized)
 	//err := hash1.Dump(foo)
@@ -6,27 +6,49 @@ package transforms
 
 import (
 	"image"
+
+	"github.com/evanoberholster/imageColor/hdr"
 )
 
 // Rgb2Gray function converts RGB to a gray scale array.
+// 16 bit and float32 images keep 16 bit precision,
+// other images are truncated to 8 bits per channel.
 func Rgb2Gray(colorImg image.Image) [][]float64 {
 	bounds := colorImg.Bounds()
 	w, h := bounds.Max.X-bounds.Min.X, bounds.Max.Y-bounds.Min.Y
 	pixels := make([][]float64, h)
+	lum := grayReader(colorImg)
 
 	for i := range pixels {
 		pixels[i] = make([]float64, w)
 		for j := range pixels[i] {
-			color := colorImg.At(j, i)
-			r, g, b, _ := color.RGBA()
-			lum := 0.299*float64(r/257) + 0.587*float64(g/257) + 0.114*float64(b/256)
-			pixels[i][j] = lum
+			pixels[i][j] = lum(bounds.Min.X+j, bounds.Min.Y+i)
 		}
 	}
 
 	return pixels
 }
 
+// grayReader returns a function that reads the luminance at x, y on a 0-255 scale.
+func grayReader(colorImg image.Image) func(x, y int) float64 {
+	switch img := colorImg.(type) {
+	case *image.Gray16:
+		return func(x, y int) float64 {
+			return float64(img.Gray16At(x, y).Y) / 257
+		}
+	case *image.RGBA64, *image.NRGBA64, *hdr.RGBA:
+		// hdr.Color.RGBA clamps and premultiplies like a conversion to RGBA64
+		return func(x, y int) float64 {
+			r, g, b, _ := img.At(x, y).RGBA()
+			return 0.299*float64(r)/257 + 0.587*float64(g)/257 + 0.114*float64(b)/257
+		}
+	}
+	return func(x, y int) float64 {
+		r, g, b, _ := colorImg.At(x, y).RGBA()
+		return 0.299*float64(r/257) + 0.587*float64(g/257) + 0.114*float64(b/256)
+	}
+}
+
 // FlattenPixels function flattens 2d array into 1d array.
 func FlattenPixels(pixels [][]float64, x int, y int) []float64 {
 	flattens := make([]float64, x*y)
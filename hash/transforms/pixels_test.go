@@ -0,0 +1,42 @@
+package transforms
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/evanoberholster/imageColor/hdr"
+)
+
+func TestRgb2Gray(t *testing.T) {
+	colors := []hdr.Color{
+		{R: 0.25, G: 0.5, B: 0.75, A: 1},
+		{R: 2, G: 1.5, B: -0.5, A: 1},    // out of range
+		{R: 0.8, G: 0.4, B: 0.2, A: 0.5}, // translucent
+		{R: 1, G: 1, B: 1, A: 0},
+	}
+	m := hdr.NewRGBA(image.Rect(0, 0, len(colors), 1))
+	rgba64 := image.NewRGBA64(m.Rect)
+	for x, c := range colors {
+		m.SetRGBA(x, 0, c)
+		rgba64.Set(x, 0, c)
+	}
+	got, want := Rgb2Gray(m), Rgb2Gray(rgba64)
+	for x := range colors {
+		if math.Abs(got[0][x]-want[0][x]) > 1e-9 {
+			t.Errorf("Gray of %v was incorrect, got: %v, want: %v.", colors[x], got[0][x], want[0][x])
+		}
+	}
+	if got[0][1] > 255 {
+		t.Errorf("Gray of %v was incorrect, got: %v, want: at most 255.", colors[1], got[0][1])
+	}
+
+	// 16 bit images are not truncated to 8 bits
+	gray16 := image.NewGray16(image.Rect(0, 0, 2, 1))
+	gray16.SetGray16(0, 0, color.Gray16{Y: 0x8000})
+	gray16.SetGray16(1, 0, color.Gray16{Y: 0x80ff})
+	if g := Rgb2Gray(gray16); g[0][0] == g[0][1] || math.Abs(g[0][0]-float64(0x8000)/257) > 1e-9 {
+		t.Errorf("Gray16 was incorrect, got: %v, want: %v.", g[0], []float64{float64(0x8000) / 257, float64(0x80ff) / 257})
+	}
+}
@@ -0,0 +1,136 @@
+// Package hdr provides a float32 RGBA image for high precision and high dynamic range data.
+package hdr
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// Color - Non alpha-premultiplied float32 color.
+// Channels are nominally in the range [0, 1] but may exceed 1 for HDR data.
+type Color struct {
+	R, G, B, A float32
+}
+
+// RGBA - Alpha-premultiplied 16 bit values, clamped to [0, 0xffff]
+func (c Color) RGBA() (r, g, b, a uint32) {
+	alpha := clamp(c.A)
+	return to16(clamp(c.R) * alpha), to16(clamp(c.G) * alpha), to16(clamp(c.B) * alpha), to16(alpha)
+}
+
+// ColorModel - Model for converting any color to Color
+var ColorModel = color.ModelFunc(colorModel)
+
+func colorModel(c color.Color) color.Color {
+	if _, ok := c.(Color); ok {
+		return c
+	}
+	return ColorFrom(c)
+}
+
+// ColorFrom - Convert a color.Color to Color without losing 16 bit precision
+func ColorFrom(c color.Color) Color {
+	switch c := c.(type) {
+	case Color:
+		return c
+	case color.NRGBA64:
+		return Color{R: float32(c.R) / 0xffff, G: float32(c.G) / 0xffff, B: float32(c.B) / 0xffff, A: float32(c.A) / 0xffff}
+	case color.NRGBA:
+		return Color{R: float32(c.R) / 0xff, G: float32(c.G) / 0xff, B: float32(c.B) / 0xff, A: float32(c.A) / 0xff}
+	}
+	r, g, b, a := c.RGBA()
+	if a == 0 {
+		return Color{}
+	}
+	fa := float32(a)
+	return Color{R: float32(r) / fa, G: float32(g) / fa, B: float32(b) / fa, A: fa / 0xffff}
+}
+
+// RGBA - In-memory image of Color values
+type RGBA struct {
+	// Pix holds the image's pixels, in R, G, B, A order.
+	// The pixel at (x, y) starts at Pix[(y-Rect.Min.Y)*Stride + (x-Rect.Min.X)*4].
+	Pix    []float32
+	Stride int
+	Rect   image.Rectangle
+}
+
+// NewRGBA - Create a new RGBA image with the given bounds
+func NewRGBA(r image.Rectangle) *RGBA {
+	return &RGBA{
+		Pix:    make([]float32, 4*r.Dx()*r.Dy()),
+		Stride: 4 * r.Dx(),
+		Rect:   r,
+	}
+}
+
+// Convert - Convert an image to RGBA, preserving 16 bit precision
+func Convert(m image.Image) *RGBA {
+	if p, ok := m.(*RGBA); ok {
+		return p
+	}
+	b := m.Bounds()
+	p := NewRGBA(b)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			p.SetRGBA(x, y, ColorFrom(m.At(x, y)))
+		}
+	}
+	return p
+}
+
+// ColorModel - Image ColorModel
+func (p *RGBA) ColorModel() color.Model { return ColorModel }
+
+// Bounds - Image Bounds
+func (p *RGBA) Bounds() image.Rectangle { return p.Rect }
+
+// At - Color at x, y
+func (p *RGBA) At(x, y int) color.Color {
+	return p.RGBAAt(x, y)
+}
+
+// RGBAAt - Color at x, y
+func (p *RGBA) RGBAAt(x, y int) Color {
+	if !(image.Point{x, y}.In(p.Rect)) {
+		return Color{}
+	}
+	i := p.PixOffset(x, y)
+	s := p.Pix[i : i+4 : i+4]
+	return Color{R: s[0], G: s[1], B: s[2], A: s[3]}
+}
+
+// PixOffset - Index of the first element of Pix that corresponds to the pixel at x, y
+func (p *RGBA) PixOffset(x, y int) int {
+	return (y-p.Rect.Min.Y)*p.Stride + (x-p.Rect.Min.X)*4
+}
+
+// Set - Set the color at x, y
+func (p *RGBA) Set(x, y int, c color.Color) {
+	p.SetRGBA(x, y, ColorFrom(c))
+}
+
+// SetRGBA - Set the Color at x, y
+func (p *RGBA) SetRGBA(x, y int, c Color) {
+	if !(image.Point{x, y}.In(p.Rect)) {
+		return
+	}
+	i := p.PixOffset(x, y)
+	s := p.Pix[i : i+4 : i+4]
+	s[0], s[1], s[2], s[3] = c.R, c.G, c.B, c.A
+}
+
+func clamp(v float32) float32 {
+	if v < 0 || v != v {
+		return 0
+	}
+	if v > 1 {
+		return 1
+	}
+	return v
+}
+
+func to16(v float32) uint32 {
+	return uint32(math.Round(float64(v) * 0xffff))
+}
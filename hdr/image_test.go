@@ -0,0 +1,75 @@
+package hdr
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestColorRGBA(t *testing.T) {
+	tests := []struct {
+		c          Color
+		r, g, b, a uint32
+	}{
+		{Color{1, 0.5, 0, 1}, 0xffff, 0x8000, 0, 0xffff},
+		{Color{2, -1, float32(math.NaN()), 1}, 0xffff, 0, 0, 0xffff}, // clamped
+		{Color{1, 1, 1, 0.5}, 0x8000, 0x8000, 0x8000, 0x8000},        // premultiplied
+		{Color{1, 1, 1, 2}, 0xffff, 0xffff, 0xffff, 0xffff},
+	}
+	for _, tt := range tests {
+		r, g, b, a := tt.c.RGBA()
+		if r != tt.r || g != tt.g || b != tt.b || a != tt.a {
+			t.Errorf("RGBA of %v was incorrect, got: %x %x %x %x, want: %x %x %x %x.", tt.c, r, g, b, a, tt.r, tt.g, tt.b, tt.a)
+		}
+	}
+}
+
+func TestColorFrom(t *testing.T) {
+	tests := []struct {
+		c    color.Color
+		want Color
+	}{
+		{color.NRGBA64{0xffff, 0x8000, 0x0001, 0xffff}, Color{1, float32(0x8000) / 0xffff, float32(1) / 0xffff, 1}},
+		{color.NRGBA{0xff, 0x80, 0, 0x80}, Color{1, float32(0x80) / 0xff, 0, float32(0x80) / 0xff}},
+		{color.RGBA64{0x4000, 0x2000, 0, 0x8000}, Color{float32(0x4000) / 0x8000, float32(0x2000) / 0x8000, 0, float32(0x8000) / 0xffff}},
+		{color.RGBA{}, Color{}},
+		{Color{2, 0, 0, 1}, Color{2, 0, 0, 1}},
+	}
+	for _, tt := range tests {
+		if got := ColorFrom(tt.c); got != tt.want {
+			t.Errorf("ColorFrom(%v) was incorrect, got: %v, want: %v.", tt.c, got, tt.want)
+		}
+	}
+	if c := ColorModel.Convert(color.Gray16{0x1234}); c != (Color{float32(0x1234) / 0xffff, float32(0x1234) / 0xffff, float32(0x1234) / 0xffff, 1}) {
+		t.Errorf("ColorModel was incorrect, got: %v.", c)
+	}
+}
+
+func TestRGBA(t *testing.T) {
+	rect := image.Rect(2, 3, 6, 5)
+	m := NewRGBA(rect)
+	if len(m.Pix) != 4*rect.Dx()*rect.Dy() || m.Bounds() != rect {
+		t.Fatalf("NewRGBA was incorrect, got: %d values %v, want: %d values %v.", len(m.Pix), m.Bounds(), 4*rect.Dx()*rect.Dy(), rect)
+	}
+	hdrColor := Color{1.5, 0.25, 0, 1}
+	m.SetRGBA(5, 4, hdrColor)
+	m.SetRGBA(0, 0, hdrColor) // outside, ignored
+	if c := m.RGBAAt(5, 4); c != hdrColor {
+		t.Errorf("RGBAAt was incorrect, got: %v, want: %v.", c, hdrColor)
+	}
+	if c := m.At(0, 0); c != (Color{}) {
+		t.Errorf("At outside the bounds was incorrect, got: %v, want: %v.", c, Color{})
+	}
+
+	// Convert keeps 16 bit values that an 8 bit image would lose
+	src := image.NewNRGBA64(rect)
+	src.SetNRGBA64(2, 3, color.NRGBA64{0x1234, 0x5678, 0x9abc, 0xffff})
+	p := Convert(src)
+	if r, g, b, a := p.At(2, 3).RGBA(); r != 0x1234 || g != 0x5678 || b != 0x9abc || a != 0xffff {
+		t.Errorf("Convert was incorrect, got: %x %x %x %x, want: %x %x %x %x.", r, g, b, a, 0x1234, 0x5678, 0x9abc, 0xffff)
+	}
+	if Convert(m) != m {
+		t.Errorf("Convert of an RGBA was incorrect, got: a copy, want: the same image.")
+	}
+}
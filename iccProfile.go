@@ -0,0 +1,314 @@
+package imagecolor
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"sort"
+)
+
+// Errors
+const (
+	ErrorNoICCProfile          = "No ICC profile found"
+	ErrorICCProfileInvalid     = "ICC profile is invalid"
+	ErrorICCProfileUnsupported = "ICC profile is not a RGB matrix/TRC profile"
+	ErrorUnknownImageFormat    = "Image format is not JPEG or PNG"
+	ErrorICCProfileTooLarge    = "ICC profile is larger than 4 MiB"
+)
+
+// maxICCProfileSize - Largest extracted ICC profile, which bounds the inflated iCCP data
+const maxICCProfileSize = 4 << 20
+
+// maxICCPChunkSize - Largest iCCP chunk read, a profile of maxICCProfileSize bytes with room
+// for its name and the zlib overhead of stored blocks
+const maxICCPChunkSize = maxICCProfileSize + maxICCProfileSize/1024 + 1024
+
+// ICC Tags
+const (
+	iccTagRedXYZ   = "rXYZ"
+	iccTagGreenXYZ = "gXYZ"
+	iccTagBlueXYZ  = "bXYZ"
+	iccTagRedTRC   = "rTRC"
+	iccTagGreenTRC = "gTRC"
+	iccTagBlueTRC  = "bTRC"
+	iccTagDesc     = "desc"
+)
+
+// iccPCSWhite - D50 white of the ICC profile connection space
+var iccPCSWhite = [3]float64{0.9642, 1.0, 0.8249}
+
+// ColorSpaceFromICC - Create a ColorSpace from an ICC profile.
+// Only RGB matrix/TRC profiles are supported (this includes sRGB, Display P3,
+// Adobe RGB and ProPhoto profiles as embedded by cameras and phones).
+func ColorSpaceFromICC(profile []byte) (*ColorSpace, error) {
+	if len(profile) < 132 || string(profile[36:40]) != "acsp" {
+		return nil, errors.New(ErrorICCProfileInvalid)
+	}
+	if string(profile[16:20]) != "RGB " {
+		return nil, errors.New(ErrorICCProfileUnsupported)
+	}
+	tags, err := iccTags(profile)
+	if err != nil {
+		return nil, err
+	}
+
+	var m [3][3]float64
+	for c, sig := range []string{iccTagRedXYZ, iccTagGreenXYZ, iccTagBlueXYZ} {
+		data, ok := tags[sig]
+		if !ok || len(data) < 20 || string(data[:4]) != "XYZ " {
+			return nil, errors.New(ErrorICCProfileUnsupported)
+		}
+		for r := 0; r < 3; r++ {
+			m[r][c] = s15Fixed16(data[8+r*4:])
+		}
+	}
+	var trc [3]toneCurve
+	for c, sig := range []string{iccTagRedTRC, iccTagGreenTRC, iccTagBlueTRC} {
+		data, ok := tags[sig]
+		if !ok {
+			return nil, errors.New(ErrorICCProfileUnsupported)
+		}
+		if trc[c], err = parseICCCurve(data); err != nil {
+			return nil, err
+		}
+	}
+	return newColorSpaceFromXYZ(iccDescription(tags[iccTagDesc]), m, iccPCSWhite, trc), nil
+}
+
+// iccTags - Map of tag signature to tag data
+func iccTags(profile []byte) (map[string][]byte, error) {
+	count := int(binary.BigEndian.Uint32(profile[128:]))
+	if count > (len(profile)-132)/12 {
+		return nil, errors.New(ErrorICCProfileInvalid)
+	}
+	tags := make(map[string][]byte, count)
+	for i := 0; i < count; i++ {
+		entry := profile[132+i*12:]
+		offset := uint64(binary.BigEndian.Uint32(entry[4:]))
+		size := uint64(binary.BigEndian.Uint32(entry[8:]))
+		if offset+size > uint64(len(profile)) {
+			return nil, errors.New(ErrorICCProfileInvalid)
+		}
+		tags[string(entry[:4])] = profile[offset : offset+size]
+	}
+	return tags, nil
+}
+
+// parseICCCurve - Parse a 'curv' or 'para' tag
+func parseICCCurve(data []byte) (toneCurve, error) {
+	if len(data) < 12 {
+		return toneCurve{}, errors.New(ErrorICCProfileInvalid)
+	}
+	switch string(data[:4]) {
+	case "curv":
+		n := int(binary.BigEndian.Uint32(data[8:]))
+		if len(data) < 12+n*2 {
+			return toneCurve{}, errors.New(ErrorICCProfileInvalid)
+		}
+		switch n {
+		case 0:
+			return toneCurve{}, nil
+		case 1:
+			return gammaCurve(float64(binary.BigEndian.Uint16(data[12:])) / 256), nil
+		}
+		table := make([]float64, n)
+		for i := range table {
+			table[i] = float64(binary.BigEndian.Uint16(data[12+i*2:])) / 65535
+		}
+		return toneCurve{table: table}, nil
+	case "para":
+		numParams := []int{1, 3, 4, 5, 7}
+		fn := int(binary.BigEndian.Uint16(data[8:]))
+		if fn >= len(numParams) || len(data) < 12+numParams[fn]*4 {
+			return toneCurve{}, errors.New(ErrorICCProfileInvalid)
+		}
+		params := make([]float64, numParams[fn])
+		for i := range params {
+			params[i] = s15Fixed16(data[12+i*4:])
+		}
+		return toneCurve{params: params}, nil
+	}
+	return toneCurve{}, errors.New(ErrorICCProfileUnsupported)
+}
+
+// iccDescription - Profile description from a 'desc' or 'mluc' tag
+func iccDescription(data []byte) string {
+	if len(data) < 12 {
+		return "ICC"
+	}
+	switch string(data[:4]) {
+	case "desc":
+		n := int(binary.BigEndian.Uint32(data[8:]))
+		if n > 0 && len(data) >= 12+n {
+			return string(bytes.TrimRight(data[12:12+n], "\x00"))
+		}
+	case "mluc":
+		if len(data) >= 28 && binary.BigEndian.Uint32(data[8:]) > 0 {
+			length := int(binary.BigEndian.Uint32(data[20:]))
+			offset := int(binary.BigEndian.Uint32(data[24:]))
+			if offset+length <= len(data) {
+				u := make([]rune, 0, length/2)
+				for i := offset; i+1 < offset+length; i += 2 {
+					u = append(u, rune(binary.BigEndian.Uint16(data[i:])))
+				}
+				return string(u)
+			}
+		}
+	}
+	return "ICC"
+}
+
+func s15Fixed16(b []byte) float64 {
+	return float64(int32(binary.BigEndian.Uint32(b))) / 65536
+}
+
+// ExtractICCProfile - Extract an embedded ICC profile from a JPEG (APP2) or PNG (iCCP) stream.
+// Returns ErrorNoICCProfile when the image has no embedded profile and
+// ErrorICCProfileTooLarge for profiles larger than 4 MiB.
+func ExtractICCProfile(r io.Reader) ([]byte, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(8)
+	if err != nil && len(magic) < 2 {
+		return nil, err
+	}
+	switch {
+	case magic[0] == 0xff && magic[1] == 0xd8:
+		return extractJPEGICCProfile(br)
+	case bytes.Equal(magic, pngSignature):
+		return extractPNGICCProfile(br)
+	}
+	return nil, errors.New(ErrorUnknownImageFormat)
+}
+
+// JPEG Markers
+const (
+	jpegMarkerSOI  = 0xd8
+	jpegMarkerEOI  = 0xd9
+	jpegMarkerSOS  = 0xda
+	jpegMarkerAPP2 = 0xe2
+)
+
+var jpegICCHeader = []byte("ICC_PROFILE\x00")
+
+// extractJPEGICCProfile - Concatenate ICC_PROFILE APP2 segments in sequence order
+func extractJPEGICCProfile(r *bufio.Reader) ([]byte, error) {
+	type chunk struct {
+		seq  byte
+		data []byte
+	}
+	var chunks []chunk
+	size := 0
+	if _, err := r.Discard(2); err != nil {
+		return nil, err
+	}
+	var hdr [4]byte
+	for {
+		if _, err := io.ReadFull(r, hdr[:2]); err != nil {
+			return nil, err
+		}
+		if hdr[0] != 0xff {
+			return nil, errors.New(ErrorUnknownImageFormat)
+		}
+		marker := hdr[1]
+		if marker == 0xff || marker == jpegMarkerSOI || (marker >= 0xd0 && marker <= 0xd7) {
+			if marker == 0xff {
+				r.UnreadByte()
+			}
+			continue
+		}
+		if marker == jpegMarkerSOS || marker == jpegMarkerEOI {
+			break
+		}
+		if _, err := io.ReadFull(r, hdr[2:]); err != nil {
+			return nil, err
+		}
+		length := int(binary.BigEndian.Uint16(hdr[2:])) - 2
+		if length < 0 {
+			return nil, errors.New(ErrorUnknownImageFormat)
+		}
+		if marker != jpegMarkerAPP2 || length < len(jpegICCHeader)+2 {
+			if _, err := r.Discard(length); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		data := make([]byte, length)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		if bytes.HasPrefix(data, jpegICCHeader) {
+			n := len(jpegICCHeader)
+			if size += len(data) - n - 2; size > maxICCProfileSize {
+				return nil, errors.New(ErrorICCProfileTooLarge)
+			}
+			chunks = append(chunks, chunk{seq: data[n], data: data[n+2:]})
+		}
+	}
+	if len(chunks) == 0 {
+		return nil, errors.New(ErrorNoICCProfile)
+	}
+	sort.Slice(chunks, func(i, j int) bool { return chunks[i].seq < chunks[j].seq })
+	var profile []byte
+	for _, c := range chunks {
+		profile = append(profile, c.data...)
+	}
+	return profile, nil
+}
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// extractPNGICCProfile - Decompress the iCCP chunk of a PNG
+func extractPNGICCProfile(r *bufio.Reader) ([]byte, error) {
+	if _, err := r.Discard(len(pngSignature)); err != nil {
+		return nil, err
+	}
+	var hdr [8]byte
+	for {
+		if _, err := io.ReadFull(r, hdr[:]); err != nil {
+			return nil, err
+		}
+		length := binary.BigEndian.Uint32(hdr[:4])
+		if length > math.MaxInt32 {
+			return nil, errors.New(ErrorUnknownImageFormat)
+		}
+		switch string(hdr[4:]) {
+		case "iCCP":
+			// A profile name of at most 79 bytes and zlib data no longer than stored (uncompressed) blocks
+			if length > maxICCPChunkSize {
+				return nil, errors.New(ErrorICCProfileTooLarge)
+			}
+			data := make([]byte, length)
+			if _, err := io.ReadFull(r, data); err != nil {
+				return nil, err
+			}
+			// profile name (null terminated), compression method, zlib data
+			i := bytes.IndexByte(data, 0)
+			if i < 0 || i+2 > len(data) || data[i+1] != 0 {
+				return nil, errors.New(ErrorICCProfileInvalid)
+			}
+			zr, err := zlib.NewReader(bytes.NewReader(data[i+2:]))
+			if err != nil {
+				return nil, err
+			}
+			defer zr.Close()
+			profile, err := io.ReadAll(io.LimitReader(zr, maxICCProfileSize+1))
+			if err != nil {
+				return nil, err
+			}
+			if len(profile) > maxICCProfileSize {
+				return nil, errors.New(ErrorICCProfileTooLarge)
+			}
+			return profile, nil
+		case "IDAT", "IEND":
+			return nil, errors.New(ErrorNoICCProfile)
+		}
+		// Skip chunk data and CRC
+		if _, err := r.Discard(int(length) + 4); err != nil {
+			return nil, err
+		}
+	}
+}
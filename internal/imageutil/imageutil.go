@@ -0,0 +1,36 @@
+// Package imageutil holds helpers shared by the commands.
+package imageutil
+
+import (
+	"image"
+	"math"
+
+	"github.com/evanoberholster/imageColor"
+)
+
+// Downscale - Downscale img so that its largest side is at most size (0 keeps the image size)
+func Downscale(img image.Image, size int) image.Image {
+	b := img.Bounds()
+	largest := MaxInt(b.Dx(), b.Dy())
+	if size <= 0 || largest <= size {
+		return img
+	}
+	scale := float64(size) / float64(largest)
+	return imagecolor.Downscale(img, MaxInt(int(float64(b.Dx())*scale), 1), MaxInt(int(float64(b.Dy())*scale), 1), imagecolor.GammaAveraging)
+}
+
+// Finite - f, or 0 when f is NaN or infinite (not representable in JSON)
+func Finite(f float64) float64 {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0
+	}
+	return f
+}
+
+// MaxInt - Larger of a and b
+func MaxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,222 @@
+package jpegdc
+
+import (
+	"fmt"
+)
+
+// Huffman Defaults
+const (
+	maxCodeLength = 16
+	lutBits       = 11 // codes up to lutBits long are decoded with a single table lookup
+)
+
+// huffman - Huffman decoding table, specified in section C
+type huffman struct {
+	defined bool
+	// lut is indexed by the next lutBits bits: value<<8 | code length, 0 for longer codes
+	lut     [1 << lutBits]uint16
+	vals    [256]uint8
+	minCode [maxCodeLength + 1]int32
+	maxCode [maxCodeLength + 1]int32 // -1 when there are no codes of the length
+	valPtr  [maxCodeLength + 1]int32
+}
+
+// parseDHT - Huffman tables of a DHT segment, specified in section B.2.4.2
+func (d *decoder) parseDHT(p []byte) error {
+	for len(p) > 0 {
+		if len(p) < 17 {
+			return fmt.Errorf("%w: DHT has wrong length", ErrInvalid)
+		}
+		tc, th := p[0]>>4, p[0]&0x0f
+		if tc > 1 || th > 3 {
+			return fmt.Errorf("%w: bad DHT table", ErrInvalid)
+		}
+		h := &d.huff[tc][th]
+		*h = huffman{defined: true}
+		counts := p[1:17]
+		total := 0
+		for _, n := range counts {
+			total += int(n)
+		}
+		if total == 0 || total > len(h.vals) || len(p) < 17+total {
+			return fmt.Errorf("%w: DHT has wrong length", ErrInvalid)
+		}
+		copy(h.vals[:], p[17:17+total])
+		p = p[17+total:]
+
+		code, k := int32(0), int32(0)
+		for l := 1; l <= maxCodeLength; l++ {
+			n := int32(counts[l-1])
+			h.maxCode[l] = -1
+			if code+n > 1<<l {
+				return fmt.Errorf("%w: bad Huffman table", ErrInvalid)
+			}
+			if n > 0 {
+				h.valPtr[l] = k
+				h.minCode[l] = code
+				h.maxCode[l] = code + n - 1
+				if l <= lutBits {
+					for c := code; c < code+n; c++ {
+						entry := uint16(h.vals[k+c-code])<<8 | uint16(l)
+						base := int(c) << (lutBits - l)
+						for i := 0; i < 1<<(lutBits-l); i++ {
+							h.lut[base+i] = entry
+						}
+					}
+				}
+				code += n
+				k += n
+			}
+			code <<= 1
+		}
+	}
+	return nil
+}
+
+// errShortData - Entropy coded data ended before the end of the scan
+var errShortData = fmt.Errorf("%w: short Huffman data", ErrInvalid)
+
+// bitReader - Reads the entropy coded data of a scan, MSB first.
+// 0xff00 stuffing is removed, and zeros are read past the next marker.
+type bitReader struct {
+	data  []byte
+	pos   int    // position of the next byte to read
+	acc   uint64 // bits left aligned
+	n     uint   // number of bits in acc
+	zeros uint   // number of zero bytes read past the end of the data
+}
+
+// fill - Read bytes until acc holds more than 56 bits
+func (br *bitReader) fill() {
+	for br.n <= 56 {
+		var b byte
+		if br.zeros == 0 && br.pos < len(br.data) {
+			b = br.data[br.pos]
+			if b != 0xff {
+				br.pos++
+			} else if br.pos+1 < len(br.data) && br.data[br.pos+1] == 0 {
+				br.pos += 2
+			} else {
+				// A marker: stay on it and read zeros
+				b = 0
+				br.zeros++
+			}
+		} else {
+			br.zeros++
+		}
+		br.acc |= uint64(b) << (56 - br.n)
+		br.n += 8
+	}
+}
+
+// overrun - Whether more bits were read than the data holds
+func (br *bitReader) overrun() bool {
+	return br.zeros*8 > br.n
+}
+
+// reset - Skip the RST marker expected at the current position, see section F.1.2.3
+func (br *bitReader) reset() error {
+	br.acc, br.n, br.zeros = 0, 0, 0
+	for br.pos+1 < len(br.data) {
+		if br.data[br.pos] == 0xff && br.data[br.pos+1] >= 0xd0 && br.data[br.pos+1] <= 0xd7 {
+			br.pos += 2
+			return nil
+		}
+		br.pos++
+	}
+	return fmt.Errorf("%w: missing RST marker", ErrInvalid)
+}
+
+func (br *bitReader) consume(n uint) {
+	br.acc <<= n
+	br.n -= n
+}
+
+// decode - Next Huffman coded value
+func (br *bitReader) decode(h *huffman) (uint8, error) {
+	if !h.defined {
+		return 0, fmt.Errorf("%w: undefined Huffman table", ErrInvalid)
+	}
+	if br.n < maxCodeLength {
+		br.fill()
+	}
+	if v := h.lut[br.acc>>(64-lutBits)]; v != 0 {
+		br.consume(uint(v & 0xff))
+		return uint8(v >> 8), nil
+	}
+	code := int32(br.acc >> (64 - maxCodeLength))
+	for l := lutBits + 1; l <= maxCodeLength; l++ {
+		if c := code >> (maxCodeLength - l); c <= h.maxCode[l] {
+			br.consume(uint(l))
+			return h.vals[h.valPtr[l]+c-h.minCode[l]], nil
+		}
+	}
+	if br.overrun() {
+		return 0, errShortData
+	}
+	return 0, fmt.Errorf("%w: bad Huffman code", ErrInvalid)
+}
+
+// receiveExtend - Signed value of the next s bits, specified in section F.2.2.1
+func (br *bitReader) receiveExtend(s uint8) int32 {
+	if s == 0 {
+		return 0
+	}
+	if br.n < uint(s) {
+		br.fill()
+	}
+	v := int32(br.acc >> (64 - uint(s)))
+	br.consume(uint(s))
+	if v < 1<<(s-1) {
+		v += -1<<s + 1
+	}
+	return v
+}
+
+// skipAC - Skip the AC coefficients of a sequential block, specified in section F.2.2.2.
+// Codes found in the lookup table are skipped together with their coefficient bits.
+func (br *bitReader) skipAC(h *huffman) error {
+	for z := 1; z < 64; z++ {
+		var rs uint8
+		if br.n < maxCodeLength+15 {
+			br.fill()
+		}
+		if v := h.lut[br.acc>>(64-lutBits)]; v != 0 {
+			rs = uint8(v >> 8)
+			br.consume(uint(v&0xff) + uint(rs&0x0f))
+		} else {
+			var err error
+			if rs, err = br.decode(h); err != nil {
+				return err
+			}
+			br.skip(rs & 0x0f)
+		}
+		if rs&0x0f == 0 {
+			if rs != 0xf0 {
+				return nil // EOB
+			}
+			z += 15 // ZRL
+			continue
+		}
+		z += int(rs >> 4)
+	}
+	return nil
+}
+
+// skip - Skip the next s bits
+func (br *bitReader) skip(s uint8) {
+	if br.n < uint(s) {
+		br.fill()
+	}
+	br.consume(uint(s))
+}
+
+// bit - Next bit
+func (br *bitReader) bit() bool {
+	if br.n == 0 {
+		br.fill()
+	}
+	b := br.acc>>63 != 0
+	br.consume(1)
+	return b
+}
@@ -0,0 +1,443 @@
+// Package jpegdc decodes JPEG images at 1/8 scale from their DC coefficients.
+//
+// The DC coefficient of a JPEG block is the mean of its 8x8 pixels, so a reduced
+// image can be built without dequantizing the AC coefficients, computing the inverse
+// DCT or converting colors at full resolution. Progressive JPEGs only need their DC
+// scans, and the AC scans are skipped entirely.
+// The result can be passed to GetImageColors, an Analyzer or the hash functions
+// instead of a fully decoded and downscaled image.
+//
+// Baseline, extended sequential and progressive Huffman coded JPEGs with Gray or
+// YCbCr components are supported. Other JPEGs (arithmetic coding, lossless, CMYK,
+// RGB, 12 bit samples) return ErrUnsupported and can be decoded with image/jpeg.
+package jpegdc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+)
+
+// Scale - Each pixel of a decoded image is the mean of a Scale x Scale block of the JPEG
+const Scale = 8
+
+// Errors
+const (
+	ErrorNotJPEG     = "Not a JPEG image"
+	ErrorUnsupported = "JPEG encoding is not supported by jpegdc"
+	ErrorInvalid     = "Invalid JPEG data"
+)
+
+// Errors that can be tested with errors.Is
+var (
+	ErrNotJPEG     = errors.New(ErrorNotJPEG)
+	ErrUnsupported = errors.New(ErrorUnsupported)
+	ErrInvalid     = errors.New(ErrorInvalid)
+)
+
+// Markers, specified in section B.1.1.3
+const (
+	sof0Marker  = 0xc0 // baseline
+	sof1Marker  = 0xc1 // extended sequential, Huffman
+	sof2Marker  = 0xc2 // progressive, Huffman
+	dhtMarker   = 0xc4
+	rst0Marker  = 0xd0
+	rst7Marker  = 0xd7
+	soiMarker   = 0xd8
+	eoiMarker   = 0xd9
+	sosMarker   = 0xda
+	dqtMarker   = 0xdb
+	driMarker   = 0xdd
+	app14Marker = 0xee // Adobe color transform
+)
+
+// component - Frame component and the DC coefficients of its blocks
+type component struct {
+	id     uint8
+	h, v   int   // sampling factors
+	tq     uint8 // quantization table
+	q      int32 // DC quantization, set on the first scan of the component
+	bw, bh int   // blocks per row and column, padded to whole MCUs
+	cw, ch int   // blocks per row and column inside the image (non-interleaved scans)
+	dc     []int32
+}
+
+type decoder struct {
+	data          []byte
+	width, height int
+	progressive   bool
+	comps         []component
+	maxH, maxV    int
+	mxx, myy      int // MCUs per row and column
+	ri            int // restart interval
+	qt            [4]int32
+	qtDefined     [4]bool
+	huff          [2][4]huffman // DC and AC tables
+	adobe         bool
+	transform     uint8
+	scans         int
+}
+
+// Decode - Decode the DC coefficients of a JPEG into an image of 1/Scale of its size
+// (rounded up). YCbCr JPEGs return an *image.YCbCr with the subsampling of the file
+// and grayscale JPEGs an *image.Gray.
+// EXIF orientation is not applied, see loader.Orientation and loader.Orient.
+func Decode(r io.Reader) (image.Image, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeBytes(data)
+}
+
+// DecodeBytes - Decode the DC coefficients of an encoded JPEG, see Decode
+func DecodeBytes(data []byte) (image.Image, error) {
+	if len(data) < 2 || data[0] != 0xff || data[1] != soiMarker {
+		return nil, ErrNotJPEG
+	}
+	d := &decoder{data: data}
+	if err := d.decode(); err != nil {
+		return nil, err
+	}
+	return d.image()
+}
+
+// decode - Read the segments of the JPEG until EOI
+func (d *decoder) decode() error {
+	data := d.data
+	pos := 2
+	for {
+		// Skip data up to the next marker and its fill bytes
+		for pos < len(data) && data[pos] != 0xff {
+			pos++
+		}
+		for pos < len(data) && data[pos] == 0xff {
+			pos++
+		}
+		if pos >= len(data) {
+			break // missing EOI
+		}
+		marker := data[pos]
+		pos++
+		if marker == eoiMarker {
+			break
+		}
+		if marker == 0 || marker == 0x01 || (marker >= rst0Marker && marker <= rst7Marker) {
+			continue // stuffed byte, TEM and RST have no segment
+		}
+		if pos+2 > len(data) {
+			return fmt.Errorf("%w: short segment", ErrInvalid)
+		}
+		n := int(binary.BigEndian.Uint16(data[pos:]))
+		if n < 2 || pos+n > len(data) {
+			return fmt.Errorf("%w: short segment", ErrInvalid)
+		}
+		seg := data[pos+2 : pos+n]
+		pos += n
+
+		var err error
+		switch marker {
+		case sof0Marker, sof1Marker, sof2Marker:
+			err = d.parseSOF(seg, marker == sof2Marker)
+		case dhtMarker:
+			err = d.parseDHT(seg)
+		case dqtMarker:
+			err = d.parseDQT(seg)
+		case driMarker:
+			if len(seg) != 2 {
+				return fmt.Errorf("%w: DRI has wrong length", ErrInvalid)
+			}
+			d.ri = int(binary.BigEndian.Uint16(seg))
+		case app14Marker:
+			if len(seg) >= 12 && bytes.HasPrefix(seg, []byte("Adobe")) {
+				d.adobe, d.transform = true, seg[11]
+			}
+		case sosMarker:
+			pos, err = d.parseSOS(seg, pos)
+		default:
+			if marker >= 0xc3 && marker <= 0xcf {
+				// Lossless, hierarchical and arithmetic coded frames, DAC and JPG
+				return ErrUnsupported
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if d.scans == 0 {
+		return fmt.Errorf("%w: missing SOS marker", ErrInvalid)
+	}
+	return nil
+}
+
+// parseSOF - Frame header, specified in section B.2.2
+func (d *decoder) parseSOF(p []byte, progressive bool) error {
+	if d.comps != nil {
+		return fmt.Errorf("%w: multiple SOF markers", ErrInvalid)
+	}
+	if len(p) < 6 {
+		return fmt.Errorf("%w: SOF has wrong length", ErrInvalid)
+	}
+	if p[0] != 8 {
+		return ErrUnsupported // 12 bit samples
+	}
+	d.progressive = progressive
+	d.height = int(binary.BigEndian.Uint16(p[1:]))
+	d.width = int(binary.BigEndian.Uint16(p[3:]))
+	nComp := int(p[5])
+	if d.width == 0 || d.height == 0 {
+		return ErrUnsupported // DNL
+	}
+	if nComp != 1 && nComp != 3 {
+		return ErrUnsupported // CMYK and YCCK
+	}
+	if len(p) != 6+3*nComp {
+		return fmt.Errorf("%w: SOF has wrong length", ErrInvalid)
+	}
+	d.comps = make([]component, nComp)
+	for i := range d.comps {
+		c := &d.comps[i]
+		c.id, c.h, c.v, c.tq = p[6+3*i], int(p[7+3*i]>>4), int(p[7+3*i]&0x0f), p[8+3*i]
+		if c.h < 1 || c.h > 4 || c.v < 1 || c.v > 4 || c.tq > 3 {
+			return fmt.Errorf("%w: bad component", ErrInvalid)
+		}
+		for _, prev := range d.comps[:i] {
+			if prev.id == c.id {
+				return fmt.Errorf("%w: repeated component identifier", ErrInvalid)
+			}
+		}
+		if nComp == 1 {
+			// A single component is non-interleaved, its sampling factors are ignored (section A.2)
+			c.h, c.v = 1, 1
+		}
+		if c.h > d.maxH {
+			d.maxH = c.h
+		}
+		if c.v > d.maxV {
+			d.maxV = c.v
+		}
+	}
+	d.mxx = (d.width + 8*d.maxH - 1) / (8 * d.maxH)
+	d.myy = (d.height + 8*d.maxV - 1) / (8 * d.maxV)
+	for i := range d.comps {
+		c := &d.comps[i]
+		c.bw, c.bh = d.mxx*c.h, d.myy*c.v
+		c.cw = ((d.width*c.h+d.maxH-1)/d.maxH + 7) / 8
+		c.ch = ((d.height*c.v+d.maxV-1)/d.maxV + 7) / 8
+		c.dc = make([]int32, c.bw*c.bh)
+	}
+	return nil
+}
+
+// parseDQT - Quantization tables, specified in section B.2.4.1. Only the DC entries are kept.
+func (d *decoder) parseDQT(p []byte) error {
+	for len(p) > 0 {
+		pq, tq := p[0]>>4, p[0]&0x0f
+		size := 1 + 64
+		if pq == 1 {
+			size = 1 + 128
+		}
+		if pq > 1 || tq > 3 || len(p) < size {
+			return fmt.Errorf("%w: bad DQT", ErrInvalid)
+		}
+		if pq == 0 {
+			d.qt[tq] = int32(p[1])
+		} else {
+			d.qt[tq] = int32(binary.BigEndian.Uint16(p[1:]))
+		}
+		d.qtDefined[tq] = true
+		p = p[size:]
+	}
+	return nil
+}
+
+// scanComponent - Component of a scan and its Huffman tables
+type scanComponent struct {
+	c      *component
+	dc, ac *huffman
+}
+
+// parseSOS - Scan header, specified in section B.2.3, and its entropy coded data
+// that starts at pos. Returns the position following the data.
+func (d *decoder) parseSOS(p []byte, pos int) (int, error) {
+	if d.comps == nil {
+		return pos, fmt.Errorf("%w: missing SOF marker", ErrInvalid)
+	}
+	if len(p) < 1 || int(p[0]) < 1 || int(p[0]) > len(d.comps) || len(p) != 4+2*int(p[0]) {
+		return pos, fmt.Errorf("%w: SOS has wrong length", ErrInvalid)
+	}
+	n := int(p[0])
+	scan := make([]scanComponent, n)
+	for i := range scan {
+		id, tables := p[1+2*i], p[2+2*i]
+		for j := range d.comps {
+			if d.comps[j].id == id {
+				scan[i].c = &d.comps[j]
+			}
+		}
+		if scan[i].c == nil || tables>>4 > 3 || tables&0x0f > 3 {
+			return pos, fmt.Errorf("%w: bad scan component", ErrInvalid)
+		}
+		scan[i].dc, scan[i].ac = &d.huff[0][tables>>4], &d.huff[1][tables&0x0f]
+	}
+	ss, se, ah, al := p[1+2*n], p[2+2*n], p[3+2*n]>>4, p[3+2*n]&0x0f
+	if !d.progressive {
+		ss, se, ah, al = 0, 63, 0, 0
+	}
+	if ss > se || se > 63 || (ss == 0 && d.progressive && se != 0) {
+		return pos, fmt.Errorf("%w: bad spectral selection", ErrInvalid)
+	}
+	d.scans++
+	if ss > 0 {
+		// Progressive AC scans are not needed
+		return skipScan(d.data, pos), nil
+	}
+	for _, sc := range scan {
+		if sc.c.q == 0 {
+			if !d.qtDefined[sc.c.tq] {
+				return pos, fmt.Errorf("%w: undefined quantization table", ErrInvalid)
+			}
+			sc.c.q = d.qt[sc.c.tq]
+		}
+	}
+
+	br := &bitReader{data: d.data, pos: pos}
+	var pred [3]int32
+	// block - Decode the DC coefficient of block i of scan component k and skip its AC coefficients
+	block := func(k, i int) error {
+		sc := scan[k]
+		if ah != 0 {
+			// DC successive approximation refinement, section G.1.2.1
+			if br.bit() {
+				sc.c.dc[i] |= 1 << al
+			}
+			return nil
+		}
+		s, err := br.decode(sc.dc)
+		if err != nil {
+			return err
+		}
+		if s > 16 {
+			return fmt.Errorf("%w: bad DC coefficient", ErrInvalid)
+		}
+		pred[k] += br.receiveExtend(s)
+		sc.c.dc[i] = pred[k] << al
+		if d.progressive {
+			return nil
+		}
+		return br.skipAC(sc.ac)
+	}
+
+	// Interleaved scans are made of MCUs, in non-interleaved scans each block is an MCU
+	units := d.mxx * d.myy
+	if n == 1 {
+		units = scan[0].c.cw * scan[0].c.ch
+	}
+	for u := 0; u < units; u++ {
+		if br.overrun() {
+			return br.pos, errShortData
+		}
+		if d.ri > 0 && u > 0 && u%d.ri == 0 {
+			if err := br.reset(); err != nil {
+				return br.pos, err
+			}
+			pred = [3]int32{}
+		}
+		if n == 1 {
+			c := scan[0].c
+			if err := block(0, (u/c.cw)*c.bw+u%c.cw); err != nil {
+				return br.pos, err
+			}
+			continue
+		}
+		mx, my := u%d.mxx, u/d.mxx
+		for k, sc := range scan {
+			for j := 0; j < sc.c.h*sc.c.v; j++ {
+				bx, by := sc.c.h*mx+j%sc.c.h, sc.c.v*my+j/sc.c.h
+				if err := block(k, by*sc.c.bw+bx); err != nil {
+					return br.pos, err
+				}
+			}
+		}
+	}
+	if br.overrun() {
+		return br.pos, errShortData
+	}
+	return br.pos, nil
+}
+
+// skipScan - Position of the first marker after pos that is not a RST marker
+func skipScan(data []byte, pos int) int {
+	for ; pos+1 < len(data); pos++ {
+		if data[pos] != 0xff {
+			continue
+		}
+		if m := data[pos+1]; m != 0 && m != 0xff && (m < rst0Marker || m > rst7Marker) {
+			return pos
+		}
+	}
+	return len(data)
+}
+
+// image - Image of the DC coefficients
+func (d *decoder) image() (image.Image, error) {
+	rect := image.Rect(0, 0, (d.width+Scale-1)/Scale, (d.height+Scale-1)/Scale)
+	if len(d.comps) == 1 {
+		c := &d.comps[0]
+		m := image.NewGray(image.Rect(0, 0, c.bw, c.bh))
+		c.fill(m.Pix, m.Stride)
+		return m.SubImage(rect), nil
+	}
+
+	if d.adobe && d.transform == 0 {
+		return nil, ErrUnsupported // RGB
+	}
+	if !d.adobe && d.comps[0].id == 'R' && d.comps[1].id == 'G' && d.comps[2].id == 'B' {
+		return nil, ErrUnsupported // RGB
+	}
+	y, cb, cr := &d.comps[0], &d.comps[1], &d.comps[2]
+	if cb.h != cr.h || cb.v != cr.v || y.h != d.maxH || y.v != d.maxV {
+		return nil, ErrUnsupported
+	}
+	var ratio image.YCbCrSubsampleRatio
+	switch (d.maxH/cb.h)<<4 | d.maxV/cb.v {
+	case 0x11:
+		ratio = image.YCbCrSubsampleRatio444
+	case 0x12:
+		ratio = image.YCbCrSubsampleRatio440
+	case 0x21:
+		ratio = image.YCbCrSubsampleRatio422
+	case 0x22:
+		ratio = image.YCbCrSubsampleRatio420
+	case 0x41:
+		ratio = image.YCbCrSubsampleRatio411
+	case 0x42:
+		ratio = image.YCbCrSubsampleRatio410
+	default:
+		return nil, ErrUnsupported
+	}
+	m := image.NewYCbCr(image.Rect(0, 0, y.bw, y.bh), ratio)
+	y.fill(m.Y, m.YStride)
+	cb.fill(m.Cb, m.CStride)
+	cr.fill(m.Cr, m.CStride)
+	return m.SubImage(rect), nil
+}
+
+// fill - Write the mean sample value of every block, the dequantized DC coefficient / 8 + 128
+func (c *component) fill(pix []uint8, stride int) {
+	for by := 0; by < c.bh; by++ {
+		row := pix[by*stride:]
+		for bx, dc := range c.dc[by*c.bw : (by+1)*c.bw] {
+			v := (dc*c.q+4)>>3 + 128
+			if v < 0 {
+				v = 0
+			} else if v > 255 {
+				v = 255
+			}
+			row[bx] = uint8(v)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package jpegdc
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// testdata - JPEG test images of the Go distribution
+var testdata = filepath.Join(runtime.GOROOT(), "src", "image", "testdata")
+
+// blockMeanDiff - Difference between the sample i of a dc plane and the mean of the
+// corresponding 8x8 block of the full plane
+func blockMeanDiff(full, dc []uint8, fullStride, dcStride, i int) float64 {
+	bx, by := i%dcStride, i/dcStride
+	sum := 0
+	for y := by * Scale; y < (by+1)*Scale; y++ {
+		for _, v := range full[y*fullStride+bx*Scale : y*fullStride+(bx+1)*Scale] {
+			sum += int(v)
+		}
+	}
+	return math.Abs(float64(sum)/(Scale*Scale) - float64(dc[i]))
+}
+
+// compareMeans - Largest and mean difference between the samples of dc and the
+// means of the 8x8 blocks of full, for the samples of the pixels of dc
+func compareMeans(t *testing.T, full, dc image.Image) (maxDiff, meanDiff float64) {
+	n := 0
+	add := func(diff float64) {
+		maxDiff = math.Max(maxDiff, diff)
+		meanDiff += diff
+		n++
+	}
+	b := dc.Bounds()
+	switch f := full.(type) {
+	case *image.YCbCr:
+		d, ok := dc.(*image.YCbCr)
+		if !ok || f.YStride != Scale*d.YStride || f.CStride != Scale*d.CStride {
+			t.Fatalf("Image was incorrect, got: %T, want: %T with 1/8 strides.", dc, full)
+		}
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				add(blockMeanDiff(f.Y, d.Y, f.YStride, d.YStride, d.YOffset(x, y)))
+				add(blockMeanDiff(f.Cb, d.Cb, f.CStride, d.CStride, d.COffset(x, y)))
+				add(blockMeanDiff(f.Cr, d.Cr, f.CStride, d.CStride, d.COffset(x, y)))
+			}
+		}
+	case *image.Gray:
+		d, ok := dc.(*image.Gray)
+		if !ok || f.Stride != Scale*d.Stride {
+			t.Fatalf("Image was incorrect, got: %T, want: %T with 1/8 strides.", dc, full)
+		}
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				add(blockMeanDiff(f.Pix, d.Pix, f.Stride, d.Stride, d.PixOffset(x, y)))
+			}
+		}
+	default:
+		t.Fatalf("Image was incorrect, got: %T, want: YCbCr or Gray.", full)
+	}
+	return maxDiff, meanDiff / float64(n)
+}
+
+// testImage - Image with gradients and sharp edges
+func testImage(width, height int) image.Image {
+	m := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := color.RGBA{uint8(x * 255 / width), uint8(y * 255 / height), uint8((x / 13 % 2) * 200), 255}
+			if (x/21+y/17)%3 == 0 {
+				c = color.RGBA{250, 240, 30, 255}
+			}
+			m.SetRGBA(x, y, c)
+		}
+	}
+	return m
+}
+
+func TestDecode(t *testing.T) {
+	for _, size := range []image.Point{{200, 120}, {131, 77}, {7, 9}} {
+		m := testImage(size.X, size.Y)
+		gray := image.NewGray(m.Bounds())
+		for y := 0; y < size.Y; y++ {
+			for x := 0; x < size.X; x++ {
+				gray.Set(x, y, m.At(x, y))
+			}
+		}
+		for _, src := range []image.Image{m, gray} {
+			var buf bytes.Buffer
+			if err := jpeg.Encode(&buf, src, &jpeg.Options{Quality: 90}); err != nil {
+				t.Fatal(err)
+			}
+			full, err := jpeg.Decode(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			dc, err := Decode(&buf)
+			if err != nil {
+				t.Fatalf("%T %v: %v", src, size, err)
+			}
+			want := image.Rect(0, 0, (size.X+7)/8, (size.Y+7)/8)
+			if dc.Bounds() != want {
+				t.Errorf("Bounds was incorrect, got: %v, want: %v.", dc.Bounds(), want)
+			}
+			if maxDiff, meanDiff := compareMeans(t, full, dc); maxDiff > 8 || meanDiff > 0.6 {
+				t.Errorf("%T %v DC was incorrect, got: max %.2f mean %.2f, want: max 8 mean 0.6.", src, size, maxDiff, meanDiff)
+			}
+		}
+	}
+}
+
+func TestDecodeTestdata(t *testing.T) {
+	files, _ := filepath.Glob(filepath.Join(testdata, "video-00*.jpeg"))
+	if len(files) == 0 {
+		t.Skip("no JPEG test images in", testdata)
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		name := filepath.Base(file)
+		dc, err := DecodeBytes(data)
+		full, fullErr := jpeg.Decode(bytes.NewReader(data))
+		if errors.Is(err, ErrUnsupported) {
+			continue // RGB, CMYK and non standard subsampling
+		}
+		if fullErr != nil {
+			if err == nil {
+				t.Logf("%s: decoded, image/jpeg returns %v", name, fullErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if maxDiff, meanDiff := compareMeans(t, full, dc); maxDiff > 8 || meanDiff > 0.6 {
+			t.Errorf("%s DC was incorrect, got: max %.2f mean %.2f, want: max 8 mean 0.6.", name, maxDiff, meanDiff)
+		}
+	}
+	for _, name := range []string{"video-001.cmyk.jpeg", "video-001.rgb.jpeg"} {
+		data, err := os.ReadFile(filepath.Join(testdata, name))
+		if err != nil {
+			continue
+		}
+		if _, err := DecodeBytes(data); !errors.Is(err, ErrUnsupported) {
+			t.Errorf("%s was incorrect, got: %v, want: %v.", name, err, ErrUnsupported)
+		}
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, testImage(64, 48), nil); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	if _, err := DecodeBytes([]byte("\x89PNG")); !errors.Is(err, ErrNotJPEG) {
+		t.Errorf("Not JPEG was incorrect, got: %v, want: %v.", err, ErrNotJPEG)
+	}
+	if _, err := DecodeBytes(data[:len(data)/2]); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Truncated was incorrect, got: %v, want: %v.", err, ErrInvalid)
+	}
+	// Corrupted data must return an error or an image, never panic
+	corrupt := make([]byte, len(data))
+	for i := 2; i < len(data); i += 3 {
+		copy(corrupt, data)
+		corrupt[i] ^= 0x5a
+		DecodeBytes(corrupt)
+		DecodeBytes(corrupt[:i])
+	}
+}
+
+func benchmarkJPEG(b *testing.B) []byte {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, testImage(2048, 1536), &jpeg.Options{Quality: 90}); err != nil {
+		b.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func BenchmarkDecode(b *testing.B) {
+	data := benchmarkJPEG(b)
+	b.Run("jpeg", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			jpeg.Decode(bytes.NewReader(data))
+		}
+	})
+	b.Run("jpegdc", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			DecodeBytes(data)
+		}
+	})
+}
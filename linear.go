@@ -0,0 +1,216 @@
+package imagecolor
+
+import (
+	"image"
+	"sort"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+// Averaging - Space in which colors are averaged.
+// Only functions that average colors take an Averaging mode: MeanColor, MeanLuminance,
+// Centroids, AverageColor and Downscale. Statistics of HSL components (MeanHue,
+// MeanSaturation, MeanLightness and the Saturation and Lightness of ProminentColors) are
+// computed on the components of the gamma encoded pixels, and ProminentColors weights are
+// pixel counts, so they are the same in both modes.
+type Averaging uint8
+
+// Averaging Modes
+const (
+	// GammaAveraging averages gamma encoded sRGB values.
+	GammaAveraging Averaging = iota
+	// LinearAveraging averages in linear light RGB and converts the result back to sRGB.
+	LinearAveraging
+)
+
+// Rec. 709 luminance coefficients
+const (
+	lumaR = 0.2126
+	lumaG = 0.7152
+	lumaB = 0.0722
+)
+
+// colorAccumulator - Running sum of colors in an Averaging space
+type colorAccumulator struct {
+	mode    Averaging
+	r, g, b float64
+	y       float64
+	n       float64
+}
+
+// add - Add a gamma encoded sRGB color with weight w
+func (acc *colorAccumulator) add(c colorful.Color, w float64) {
+	r, g, b := c.R, c.G, c.B
+	if acc.mode == LinearAveraging {
+		r, g, b = c.LinearRgb()
+	}
+	acc.r += r * w
+	acc.g += g * w
+	acc.b += b * w
+	acc.y += (lumaR*r + lumaG*g + lumaB*b) * w
+	acc.n += w
+}
+
+// mean - Mean color as gamma encoded sRGB
+func (acc *colorAccumulator) mean() colorful.Color {
+	if acc.n == 0 {
+		return colorful.Color{}
+	}
+	r, g, b := acc.r/acc.n, acc.g/acc.n, acc.b/acc.n
+	if acc.mode == LinearAveraging {
+		return colorful.LinearRgb(r, g, b)
+	}
+	return colorful.Color{R: r, G: g, B: b}
+}
+
+// luminance - Mean luminance, gamma encoded for reporting
+func (acc *colorAccumulator) luminance() float64 {
+	if acc.n == 0 {
+		return 0
+	}
+	y := acc.y / acc.n
+	if acc.mode == LinearAveraging {
+		return colorful.LinearRgb(y, y, y).R
+	}
+	return y
+}
+
+// MeanColor - Mean color of ImageColors averaged in the given mode
+func (ic ImageColors) MeanColor(mode Averaging) ColorHSL {
+	acc := ic.accumulate(mode, nil)
+	return NewColorHSL(acc.mean())
+}
+
+// MeanLuminance - Mean relative luminance of ImageColors averaged in the given mode.
+// The result is gamma encoded so that both modes are reported on the same scale.
+func (ic ImageColors) MeanLuminance(mode Averaging) float64 {
+	acc := ic.accumulate(mode, nil)
+	return acc.luminance()
+}
+
+// accumulate - Accumulate ImageColors, optionally only the pixels where include returns true
+func (ic ImageColors) accumulate(mode Averaging, include func(x, y int) bool) colorAccumulator {
+	acc := colorAccumulator{mode: mode}
+	for x := range ic {
+		for y, c := range ic[x] {
+			if include == nil || include(x, y) {
+				acc.add(c.Colorful(), 1)
+			}
+		}
+	}
+	return acc
+}
+
+// Colorful - Convert ColorHSL to colorful.Color
+func (c ColorHSL) Colorful() colorful.Color {
+	return colorful.Hsl(c[hueValue], c[saturationValue], c[lightValue])
+}
+
+// Centroids - Mean color of the pixels of each MaterialColor in the ColorClassMap,
+// averaged in the given mode. ic must be the ImageColors the ColorClassMap was created from.
+func (cm *ColorClassMap) Centroids(ic ImageColors, mode Averaging) map[MaterialColor]ColorHSL {
+	accs := make(map[MaterialColor]*colorAccumulator)
+	for x := 0; x < cm.Width; x++ {
+		for y := 0; y < cm.Height; y++ {
+			mc := cm.At(x, y)
+			acc, ok := accs[mc]
+			if !ok {
+				acc = &colorAccumulator{mode: mode}
+				accs[mc] = acc
+			}
+			acc.add(ic[x][y].Colorful(), 1)
+		}
+	}
+	res := make(map[MaterialColor]ColorHSL, len(accs))
+	for mc, acc := range accs {
+		res[mc] = NewColorHSL(acc.mean())
+	}
+	return res
+}
+
+// DominantColor - Mean color of the pixels of a MaterialColor class and the fraction of
+// the image they cover
+type DominantColor struct {
+	Class MaterialColor
+	Color ColorHSL
+	W     float64
+}
+
+// DominantColors - Dominant colors sorted by weight, largest first
+type DominantColors []DominantColor
+
+// DominantColors - Centroids of the classes of the ColorClassMap with a weight above limit,
+// averaged in the given mode. ic must be the ImageColors the ColorClassMap was created from.
+func (cm *ColorClassMap) DominantColors(ic ImageColors, mode Averaging, limit float64) DominantColors {
+	weights := cm.Weights()
+	var res DominantColors
+	for mc, c := range cm.Centroids(ic, mode) {
+		if w := weights[mc]; w > limit {
+			res = append(res, DominantColor{Class: mc, Color: c, W: w})
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].W == res[j].W {
+			return res[i].Class < res[j].Class
+		}
+		return res[i].W > res[j].W
+	})
+	return res
+}
+
+// AverageColor - Average color of an image averaged in the given mode
+func AverageColor(m image.Image, mode Averaging) colorful.Color {
+	bounds := m.Bounds()
+	pixel := pixelReader(m)
+	acc := colorAccumulator{mode: mode}
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			acc.add(pixel(x, y), 1)
+		}
+	}
+	return acc.mean()
+}
+
+// Downscale - Downscale an image to width x height with an area averaging filter.
+// Use LinearAveraging to avoid darkening high contrast detail.
+func Downscale(m image.Image, width, height int, mode Averaging) *image.RGBA {
+	bounds := m.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	if width <= 0 || height <= 0 || bounds.Empty() {
+		return dst
+	}
+	pixel := pixelReader(m)
+	sx := float64(bounds.Dx()) / float64(width)
+	sy := float64(bounds.Dy()) / float64(height)
+	for dy := 0; dy < height; dy++ {
+		y0, y1 := float64(dy)*sy, float64(dy+1)*sy
+		for dx := 0; dx < width; dx++ {
+			x0, x1 := float64(dx)*sx, float64(dx+1)*sx
+			acc := colorAccumulator{mode: mode}
+			for y := int(y0); float64(y) < y1 && y < bounds.Dy(); y++ {
+				wy := overlap(float64(y), y0, y1)
+				for x := int(x0); float64(x) < x1 && x < bounds.Dx(); x++ {
+					w := wy * overlap(float64(x), x0, x1)
+					acc.add(pixel(x+bounds.Min.X, y+bounds.Min.Y), w)
+				}
+			}
+			dst.Set(dx, dy, acc.mean().Clamped())
+		}
+	}
+	return dst
+}
+
+// overlap - Length of the overlap of pixel [p, p+1) with [a, b)
+func overlap(p, a, b float64) float64 {
+	lo, hi := p, p+1
+	if a > lo {
+		lo = a
+	}
+	if b < hi {
+		hi = b
+	}
+	if hi < lo {
+		return 0
+	}
+	return hi - lo
+}
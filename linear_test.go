@@ -0,0 +1,98 @@
+package imagecolor
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+// testCheckerboard - Black and white checkerboard of single pixels
+func testCheckerboard(width, height int) *image.RGBA {
+	m := testFill(width, height, color.RGBA{0, 0, 0, 0xff})
+	for y := 0; y < height; y++ {
+		for x := (y + 1) % 2; x < width; x += 2 {
+			m.SetRGBA(x, y, color.RGBA{0xff, 0xff, 0xff, 0xff})
+		}
+	}
+	return m
+}
+
+// linearGray - sRGB value of the linear light average of black and white
+var linearGray = colorful.LinearRgb(0.5, 0.5, 0.5).R
+
+func TestAverageColor(t *testing.T) {
+	m := testCheckerboard(8, 8)
+	tests := []struct {
+		mode Averaging
+		want float64
+	}{
+		{GammaAveraging, 0.5},
+		{LinearAveraging, linearGray}, // about 0.735
+	}
+	for _, tt := range tests {
+		if c := AverageColor(m, tt.mode); math.Abs(c.R-tt.want) > 1e-9 || c.R != c.G || c.G != c.B {
+			t.Errorf("AverageColor %v was incorrect, got: %v, want: %v.", tt.mode, c, tt.want)
+		}
+		ic := GetImageColors(m)
+		if l := ic.MeanColor(tt.mode)[lightValue]; math.Abs(l-tt.want) > 1e-9 {
+			t.Errorf("MeanColor %v was incorrect, got: %v, want: %v.", tt.mode, l, tt.want)
+		}
+		// The luminance of gray is its value
+		if y := ic.MeanLuminance(tt.mode); math.Abs(y-tt.want) > 1e-9 {
+			t.Errorf("MeanLuminance %v was incorrect, got: %v, want: %v.", tt.mode, y, tt.want)
+		}
+		d := Downscale(m, 4, 4, tt.mode)
+		if v := d.RGBAAt(1, 2).R; math.Abs(float64(v)-tt.want*0xff) > 0.5 {
+			t.Errorf("Downscale %v was incorrect, got: %v, want: %v.", tt.mode, v, math.Round(tt.want*0xff))
+		}
+	}
+
+	// Red and blue halves average to a brighter purple in linear light
+	red, blue := colorful.Color{R: 1}, colorful.Color{B: 1}
+	m = image.NewRGBA(image.Rect(0, 0, 2, 1))
+	m.Set(0, 0, red)
+	m.Set(1, 0, blue)
+	want := colorful.LinearRgb(0.5, 0, 0.5)
+	if c := AverageColor(m, LinearAveraging); math.Abs(c.R-want.R) > 1e-9 || c.G != 0 || math.Abs(c.B-want.B) > 1e-9 {
+		t.Errorf("AverageColor was incorrect, got: %v, want: %v.", c, want)
+	}
+}
+
+func TestCentroids(t *testing.T) {
+	// Classes are black and white, and each class centroid is its own color in both modes
+	ic := GetImageColors(testCheckerboard(6, 4))
+	cm := ic.ClassMap()
+	for _, mode := range []Averaging{GammaAveraging, LinearAveraging} {
+		centroids := cm.Centroids(*ic, mode)
+		if len(centroids) != 2 {
+			t.Fatalf("Centroids %v was incorrect, got: %v, want: %v classes.", mode, centroids, 2)
+		}
+		for mc, c := range centroids {
+			if l := c[lightValue]; math.Abs(l-math.Round(l)) > 1e-9 {
+				t.Errorf("Centroid %v %v was incorrect, got: %v, want: %v.", mode, mc, l, "0 or 1")
+			}
+		}
+	}
+
+	// A class of two grays averages to the mean of their linear values
+	m := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	m.SetRGBA(0, 0, color.RGBA{0x60, 0x60, 0x60, 0xff})
+	m.SetRGBA(1, 0, color.RGBA{0x70, 0x70, 0x70, 0xff})
+	ic = GetImageColors(m)
+	cm = ic.ClassMap()
+	if cm.At(0, 0) != cm.At(1, 0) {
+		t.Fatalf("Classes was incorrect, got: %v and %v, want: one class.", cm.At(0, 0), cm.At(1, 0))
+	}
+	lo, _, _ := colorful.Color{R: 0x60 / 255.0}.LinearRgb()
+	hi, _, _ := colorful.Color{R: 0x70 / 255.0}.LinearRgb()
+	want := colorful.LinearRgb((lo+hi)/2, 0, 0).R
+	if l := cm.Centroids(*ic, LinearAveraging)[cm.At(0, 0)][lightValue]; math.Abs(l-want) > 1e-9 {
+		t.Errorf("Linear centroid was incorrect, got: %v, want: %v.", l, want)
+	}
+	if l := cm.Centroids(*ic, GammaAveraging)[cm.At(0, 0)][lightValue]; math.Abs(l-0x68/255.0) > 1e-9 {
+		t.Errorf("Gamma centroid was incorrect, got: %v, want: %v.", l, 0x68/255.0)
+	}
+}
@@ -0,0 +1,121 @@
+// Package loader decodes images of any registered format (JPEG, PNG, GIF, BMP, TIFF and WebP)
+// and applies their EXIF orientation, so that images are analyzed the way they are displayed.
+package loader
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"os"
+
+	// Image formats decoded by the loader
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/tiff"
+	_ "golang.org/x/image/webp"
+)
+
+// Errors
+const (
+	ErrorUnknownFormat = "Image format is not supported"
+	ErrorTooManyPixels = "Image has too many pixels"
+	ErrorTooLarge      = "Image file is too large"
+	ErrorEmptyImage    = "Image is empty"
+)
+
+// Errors that can be tested with errors.Is
+var (
+	ErrUnknownFormat = errors.New(ErrorUnknownFormat)
+	ErrTooManyPixels = errors.New(ErrorTooManyPixels)
+	ErrTooLarge      = errors.New(ErrorTooLarge)
+	ErrEmptyImage    = errors.New(ErrorEmptyImage)
+)
+
+// Metadata - Properties of a decoded image
+type Metadata struct {
+	Format      string      // name of the registered format: "jpeg", "png", "gif", "bmp", "tiff" or "webp"
+	Width       int         // width of the decoded image, after orientation
+	Height      int         // height of the decoded image, after orientation
+	ColorModel  color.Model // color model of the decoded file
+	Orientation int         // EXIF orientation (1-8) of the file, 1 when missing
+}
+
+// Loader - Decodes images. The zero value decodes images of any size and applies their orientation.
+type Loader struct {
+	MaxBytes          int64 // files larger than MaxBytes are rejected, 0 for no limit
+	MaxPixels         int   // images with more pixels are rejected before decoding, 0 for no limit
+	IgnoreOrientation bool  // keep images as stored instead of applying their EXIF orientation
+}
+
+// Decode - Decode an image from r with the default Loader
+func Decode(r io.Reader) (image.Image, Metadata, error) {
+	return Loader{}.Decode(r)
+}
+
+// Open - Decode the image file at path with the default Loader
+func Open(path string) (image.Image, Metadata, error) {
+	return Loader{}.Open(path)
+}
+
+// Open - Decode the image file at path
+func (l Loader) Open(path string) (image.Image, Metadata, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer f.Close()
+	img, md, err := l.Decode(f)
+	if err != nil {
+		return nil, md, fmt.Errorf("%s: %w", path, err)
+	}
+	return img, md, nil
+}
+
+// Decode - Decode an image from r.
+// The header is checked against MaxPixels before the image is decoded.
+func (l Loader) Decode(r io.Reader) (image.Image, Metadata, error) {
+	var md Metadata
+	if l.MaxBytes > 0 {
+		r = io.LimitReader(r, l.MaxBytes+1)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, md, err
+	}
+	if l.MaxBytes > 0 && int64(len(data)) > l.MaxBytes {
+		return nil, md, ErrTooLarge
+	}
+
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if errors.Is(err, image.ErrFormat) {
+		return nil, md, ErrUnknownFormat
+	}
+	if err != nil {
+		return nil, md, fmt.Errorf("decoding %s: %w", format, err)
+	}
+	md = Metadata{Format: format, Width: cfg.Width, Height: cfg.Height, ColorModel: cfg.ColorModel, Orientation: 1}
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return nil, md, ErrEmptyImage
+	}
+	if l.MaxPixels > 0 && cfg.Width*cfg.Height > l.MaxPixels {
+		return nil, md, ErrTooManyPixels
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, md, fmt.Errorf("decoding %s: %w", format, err)
+	}
+	md.Orientation = Orientation(data, format)
+	if !l.IgnoreOrientation {
+		img = Orient(img, md.Orientation)
+	}
+	b := img.Bounds()
+	md.Width, md.Height = b.Dx(), b.Dy()
+	return img, md, nil
+}
@@ -0,0 +1,216 @@
+package loader
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"testing"
+
+	"golang.org/x/image/bmp"
+	"golang.org/x/image/tiff"
+)
+
+var (
+	red   = color.RGBA{255, 0, 0, 255}
+	green = color.RGBA{0, 255, 0, 255}
+	blue  = color.RGBA{0, 0, 255, 255}
+	white = color.RGBA{255, 255, 255, 255}
+)
+
+// quadrants - 64x32 image with red, green, blue and white quadrants
+// (top left, top right, bottom left, bottom right)
+func quadrants() *image.RGBA {
+	m := image.NewRGBA(image.Rect(0, 0, 64, 32))
+	for y := 0; y < 32; y++ {
+		for x := 0; x < 64; x++ {
+			c := [2][2]color.RGBA{{red, green}, {blue, white}}[y/16][x/32]
+			m.SetRGBA(x, y, c)
+		}
+	}
+	return m
+}
+
+// exifTIFF - Big endian TIFF data with a single orientation tag
+func exifTIFF(o int) []byte {
+	b := []byte("MM\x00\x2a\x00\x00\x00\x08\x00\x01")
+	entry := make([]byte, 12)
+	binary.BigEndian.PutUint16(entry, orientationTag)
+	binary.BigEndian.PutUint16(entry[2:], tiffShort)
+	binary.BigEndian.PutUint32(entry[4:], 1)
+	binary.BigEndian.PutUint16(entry[8:], uint16(o))
+	return append(append(b, entry...), 0, 0, 0, 0)
+}
+
+// jpegWithOrientation - JPEG of m with an EXIF APP1 segment
+func jpegWithOrientation(t *testing.T, m image.Image, o int) []byte {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, m, &jpeg.Options{Quality: 95}); err != nil {
+		t.Fatal(err)
+	}
+	app1 := append(append([]byte{}, exifHeader...), exifTIFF(o)...)
+	seg := []byte{0xff, 0xe1, 0, 0}
+	binary.BigEndian.PutUint16(seg[2:], uint16(len(app1)+2))
+	data := buf.Bytes()
+	return append(append(append([]byte{}, data[:2]...), append(seg, app1...)...), data[2:]...)
+}
+
+// pngWithOrientation - PNG of m with an eXIf chunk after IHDR
+func pngWithOrientation(t *testing.T, m image.Image, o int) []byte {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, m); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	const ihdrEnd = 8 + 8 + 13 + 4
+	payload := exifTIFF(o)
+	chunk := make([]byte, 8, 12+len(payload))
+	binary.BigEndian.PutUint32(chunk, uint32(len(payload)))
+	copy(chunk[4:], "eXIf")
+	chunk = append(chunk, payload...)
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+	return append(append(append([]byte{}, data[:ihdrEnd]...), chunk...), data[ihdrEnd:]...)
+}
+
+func TestOrientation(t *testing.T) {
+	// colors at the top left, top right, bottom left and bottom right of the displayed image
+	tests := []struct {
+		orientation int
+		corners     [4]color.RGBA
+	}{
+		{1, [4]color.RGBA{red, green, blue, white}},
+		{2, [4]color.RGBA{green, red, white, blue}},
+		{3, [4]color.RGBA{white, blue, green, red}},
+		{4, [4]color.RGBA{blue, white, red, green}},
+		{5, [4]color.RGBA{red, blue, green, white}},
+		{6, [4]color.RGBA{blue, red, white, green}},
+		{7, [4]color.RGBA{white, green, blue, red}},
+		{8, [4]color.RGBA{green, white, red, blue}},
+	}
+	for _, tt := range tests {
+		for format, data := range map[string][]byte{
+			"jpeg": jpegWithOrientation(t, quadrants(), tt.orientation),
+			"png":  pngWithOrientation(t, quadrants(), tt.orientation),
+		} {
+			img, md, err := Decode(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("%s %d: %v", format, tt.orientation, err)
+			}
+			if md.Orientation != tt.orientation || md.Format != format {
+				t.Errorf("Metadata was incorrect, got: %+v, want: %v %v.", md, format, tt.orientation)
+			}
+			w, h := 64, 32
+			if tt.orientation >= 5 {
+				w, h = 32, 64
+			}
+			if b := img.Bounds(); b.Dx() != w || b.Dy() != h || md.Width != w || md.Height != h {
+				t.Errorf("%s %d size was incorrect, got: %v, want: %vx%v.", format, tt.orientation, b, w, h)
+			}
+			points := []image.Point{{w / 4, h / 4}, {w * 3 / 4, h / 4}, {w / 4, h * 3 / 4}, {w * 3 / 4, h * 3 / 4}}
+			for i, p := range points {
+				if !similar(img.At(p.X, p.Y), tt.corners[i]) {
+					t.Errorf("%s %d corner %d was incorrect, got: %v, want: %v.", format, tt.orientation, i, img.At(p.X, p.Y), tt.corners[i])
+				}
+			}
+		}
+	}
+
+	img, md, err := Loader{IgnoreOrientation: true}.Decode(bytes.NewReader(jpegWithOrientation(t, quadrants(), 6)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); md.Orientation != 6 || b.Dx() != 64 {
+		t.Errorf("IgnoreOrientation was incorrect, got: %v %v, want: %v %v.", md.Orientation, b.Dx(), 6, 64)
+	}
+}
+
+func similar(c color.Color, want color.RGBA) bool {
+	r, g, b, _ := c.RGBA()
+	d := func(v uint32, w uint8) bool { return int(v>>8)-int(w) < 24 && int(w)-int(v>>8) < 24 }
+	return d(r, want.R) && d(g, want.G) && d(b, want.B)
+}
+
+func TestFormats(t *testing.T) {
+	m := quadrants()
+	gray := image.NewGray16(image.Rect(0, 0, 3, 2))
+	tests := []struct {
+		format string
+		encode func(io.Writer) error
+		model  color.Model
+	}{
+		{"png", func(w io.Writer) error { return png.Encode(w, m) }, color.RGBAModel},
+		{"png", func(w io.Writer) error { return png.Encode(w, gray) }, color.Gray16Model},
+		{"gif", func(w io.Writer) error { return gif.Encode(w, m, nil) }, nil},
+		{"bmp", func(w io.Writer) error { return bmp.Encode(w, m) }, color.RGBAModel},
+		{"tiff", func(w io.Writer) error { return tiff.Encode(w, m, nil) }, color.RGBAModel},
+		{"jpeg", func(w io.Writer) error { return jpeg.Encode(w, m, nil) }, color.YCbCrModel},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := tt.encode(&buf); err != nil {
+			t.Fatal(err)
+		}
+		img, md, err := Decode(&buf)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.format, err)
+		}
+		if md.Format != tt.format || md.Orientation != 1 || img.Bounds().Size() != image.Pt(md.Width, md.Height) {
+			t.Errorf("Metadata was incorrect, got: %+v, want: %v.", md, tt.format)
+		}
+		if tt.model != nil && md.ColorModel != tt.model {
+			t.Errorf("%s ColorModel was incorrect, got: %T, want: %T.", tt.format, md.ColorModel, tt.model)
+		}
+	}
+}
+
+func TestOrientKeepsType(t *testing.T) {
+	src := image.NewGray16(image.Rect(10, 10, 13, 12))
+	src.SetGray16(10, 10, color.Gray16{Y: 0x1234})
+	dst, ok := Orient(src, 6).(*image.Gray16)
+	if !ok {
+		t.Fatalf("Orient was incorrect, got: %T, want: %T.", Orient(src, 6), src)
+	}
+	if dst.Rect != image.Rect(0, 0, 2, 3) || dst.Gray16At(1, 0).Y != 0x1234 {
+		t.Errorf("Orient was incorrect, got: %v %v, want: %v %v.", dst.Rect, dst.Gray16At(1, 0), image.Rect(0, 0, 2, 3), 0x1234)
+	}
+	if Orient(src, 1) != image.Image(src) {
+		t.Errorf("Orient was incorrect, got: a copy, want: the same image.")
+	}
+}
+
+func TestErrors(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, quadrants()); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	tests := []struct {
+		name   string
+		loader Loader
+		data   []byte
+		err    error
+	}{
+		{"unknown format", Loader{}, []byte("not an image"), ErrUnknownFormat},
+		{"too many pixels", Loader{MaxPixels: 64*32 - 1}, data, ErrTooManyPixels},
+		{"too large", Loader{MaxBytes: int64(len(data) - 1)}, data, ErrTooLarge},
+		{"limits", Loader{MaxPixels: 64 * 32, MaxBytes: int64(len(data))}, data, nil},
+	}
+	for _, tt := range tests {
+		_, _, err := tt.loader.Decode(bytes.NewReader(tt.data))
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s was incorrect, got: %v, want: %v.", tt.name, err, tt.err)
+		}
+	}
+	if _, _, err := Decode(bytes.NewReader(data[:len(data)/2])); err == nil {
+		t.Errorf("Truncated was incorrect, got: %v, want: an error.", err)
+	}
+	if _, _, err := Open("missing.png"); err == nil {
+		t.Errorf("Open was incorrect, got: %v, want: an error.", err)
+	}
+}
@@ -0,0 +1,230 @@
+package loader
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/draw"
+)
+
+// EXIF Defaults
+const (
+	orientationTag = 0x0112
+	tiffShort      = 3
+)
+
+// exifHeader - Prefix of the EXIF data of JPEG APP1 segments (and some WebP EXIF chunks)
+var exifHeader = []byte("Exif\x00\x00")
+
+// Orientation - EXIF orientation (1-8) of an encoded image of format, 1 when it has none.
+// EXIF data is read from JPEG APP1 segments, PNG eXIf chunks, WebP EXIF chunks and TIFF IFD0.
+func Orientation(data []byte, format string) int {
+	var tiff []byte
+	switch format {
+	case "jpeg":
+		tiff = jpegExif(data)
+	case "png":
+		tiff = pngExif(data)
+	case "webp":
+		tiff = webpExif(data)
+	case "tiff":
+		tiff = data
+	}
+	if o := tiffOrientation(tiff); o >= 1 && o <= 8 {
+		return o
+	}
+	return 1
+}
+
+// jpegExif - TIFF data of the EXIF APP1 segment of a JPEG
+func jpegExif(data []byte) []byte {
+	if len(data) < 2 || data[0] != 0xff || data[1] != 0xd8 {
+		return nil
+	}
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xff {
+			return nil
+		}
+		marker := data[i+1]
+		if marker == 0xff { // fill byte
+			i++
+			continue
+		}
+		if marker == 0xda || marker == 0xd9 { // start of scan, end of image
+			return nil
+		}
+		n := int(binary.BigEndian.Uint16(data[i+2:]))
+		end := i + 2 + n
+		if n < 2 || end > len(data) {
+			return nil
+		}
+		if seg := data[i+4 : end]; marker == 0xe1 && bytes.HasPrefix(seg, exifHeader) {
+			return seg[len(exifHeader):]
+		}
+		i = end
+	}
+	return nil
+}
+
+// pngExif - Data of the eXIf chunk of a PNG
+func pngExif(data []byte) []byte {
+	const signature = 8
+	for i := signature; i+8 <= len(data); {
+		n := int(binary.BigEndian.Uint32(data[i:]))
+		typ := string(data[i+4 : i+8])
+		end := i + 8 + n
+		if n < 0 || end > len(data) || typ == "IDAT" || typ == "IEND" {
+			return nil
+		}
+		if typ == "eXIf" {
+			return data[i+8 : end]
+		}
+		i = end + 4 // crc
+	}
+	return nil
+}
+
+// webpExif - Data of the EXIF chunk of a WebP
+func webpExif(data []byte) []byte {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil
+	}
+	for i := 12; i+8 <= len(data); {
+		typ := string(data[i : i+4])
+		n := int(binary.LittleEndian.Uint32(data[i+4:]))
+		end := i + 8 + n
+		if n < 0 || end > len(data) {
+			return nil
+		}
+		if typ == "EXIF" {
+			return bytes.TrimPrefix(data[i+8:end], exifHeader)
+		}
+		i = end + n&1 // chunks are padded to an even size
+	}
+	return nil
+}
+
+// tiffOrientation - Orientation tag of the first IFD of TIFF data, 0 when missing
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 0
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+	if order.Uint16(tiff[2:]) != 42 {
+		return 0
+	}
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 0
+	}
+	entries := int(order.Uint16(tiff[ifd:]))
+	for e := 0; e < entries; e++ {
+		entry := ifd + 2 + e*12
+		if entry+12 > len(tiff) {
+			return 0
+		}
+		if order.Uint16(tiff[entry:]) == orientationTag && order.Uint16(tiff[entry+2:]) == tiffShort {
+			return int(order.Uint16(tiff[entry+8:]))
+		}
+	}
+	return 0
+}
+
+// raster - Pixels of an image with a fixed number of bytes per pixel
+type raster struct {
+	img    image.Image
+	pix    []uint8
+	stride int
+	bpp    int
+}
+
+// newRaster - Raster of img. Images that are not RGBA, NRGBA, Gray or their 16 bit
+// variants are converted to RGBA.
+func newRaster(img image.Image) raster {
+	switch m := img.(type) {
+	case *image.RGBA:
+		return raster{m, m.Pix[m.PixOffset(m.Rect.Min.X, m.Rect.Min.Y):], m.Stride, 4}
+	case *image.NRGBA:
+		return raster{m, m.Pix[m.PixOffset(m.Rect.Min.X, m.Rect.Min.Y):], m.Stride, 4}
+	case *image.Gray:
+		return raster{m, m.Pix[m.PixOffset(m.Rect.Min.X, m.Rect.Min.Y):], m.Stride, 1}
+	case *image.RGBA64:
+		return raster{m, m.Pix[m.PixOffset(m.Rect.Min.X, m.Rect.Min.Y):], m.Stride, 8}
+	case *image.NRGBA64:
+		return raster{m, m.Pix[m.PixOffset(m.Rect.Min.X, m.Rect.Min.Y):], m.Stride, 8}
+	case *image.Gray16:
+		return raster{m, m.Pix[m.PixOffset(m.Rect.Min.X, m.Rect.Min.Y):], m.Stride, 2}
+	}
+	b := img.Bounds()
+	m := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(m, m.Rect, img, b.Min, draw.Src)
+	return newRaster(m)
+}
+
+// newRasterLike - Empty raster of the same image type as r
+func newRasterLike(r raster, width, height int) raster {
+	rect := image.Rect(0, 0, width, height)
+	switch r.img.(type) {
+	case *image.NRGBA:
+		return newRaster(image.NewNRGBA(rect))
+	case *image.Gray:
+		return newRaster(image.NewGray(rect))
+	case *image.RGBA64:
+		return newRaster(image.NewRGBA64(rect))
+	case *image.NRGBA64:
+		return newRaster(image.NewNRGBA64(rect))
+	case *image.Gray16:
+		return newRaster(image.NewGray16(rect))
+	}
+	return newRaster(image.NewRGBA(rect))
+}
+
+// Orient - Transform img stored with EXIF orientation o (1-8) to its display orientation.
+// img is returned unchanged for orientation 1 and unknown orientations.
+func Orient(img image.Image, o int) image.Image {
+	if o < 2 || o > 8 {
+		return img
+	}
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dw, dh := w, h
+	if o >= 5 { // orientations 5 to 8 swap width and height
+		dw, dh = h, w
+	}
+	src := newRaster(img)
+	dst := newRasterLike(src, dw, dh)
+	bpp := src.bpp
+	for y := 0; y < h; y++ {
+		row := src.pix[y*src.stride:]
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch o {
+			case 2: // mirrored horizontally
+				dx, dy = w-1-x, y
+			case 3: // rotated 180
+				dx, dy = w-1-x, h-1-y
+			case 4: // mirrored vertically
+				dx, dy = x, h-1-y
+			case 5: // transposed
+				dx, dy = y, x
+			case 6: // rotated 90 clockwise to display
+				dx, dy = h-1-y, x
+			case 7: // transversed
+				dx, dy = h-1-y, w-1-x
+			case 8: // rotated 90 counter-clockwise to display
+				dx, dy = y, w-1-x
+			}
+			i := dy*dst.stride + dx*bpp
+			copy(dst.pix[i:i+bpp], row[x*bpp:x*bpp+bpp])
+		}
+	}
+	return dst.img
+}
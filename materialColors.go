@@ -1,5 +1,7 @@
 package imagecolor
 
+import "github.com/lucasb-eyer/go-colorful"
+
 // Material Color Series
 const (
 	materialRed MaterialColor = iota
@@ -33,6 +35,23 @@ func (mc MaterialColor) String() string {
 	return materialColorsName[mc]
 }
 
+// Colorful - Representative color of the MaterialColor (500 series)
+func (mc MaterialColor) Colorful() colorful.Color {
+	switch mc {
+	case materialWhite:
+		return colorful.Color{R: 1, G: 1, B: 1}
+	case materialBlack:
+		return colorful.Color{}
+	}
+	c := materialColors500Series[mc]
+	return colorful.Hsl(c[hueValue], c[saturationValue], c[lightValue])
+}
+
+// Hex - Hex value of the MaterialColor (500 series), ex: "#f44336"
+func (mc MaterialColor) Hex() string {
+	return mc.Colorful().Hex()
+}
+
 var (
 	materialColorsSeries = map[int]map[MaterialColor]ColorHSL{
 		100: materialColors100Series,
@@ -0,0 +1,51 @@
+package imagecolor
+
+import (
+	"math"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+// OkLab - Color in the OKLab colorspace (Björn Ottosson 2020).
+// L is in the range [0, 1], a and b are about [-0.4, 0.4].
+func OkLab(c colorful.Color) (l, a, b float64) {
+	r, g, bl := c.LinearRgb()
+	lc := math.Cbrt(0.4122214708*r + 0.5363325363*g + 0.0514459929*bl)
+	mc := math.Cbrt(0.2119034982*r + 0.6806995451*g + 0.1073969566*bl)
+	sc := math.Cbrt(0.0883024619*r + 0.2817188376*g + 0.6299787005*bl)
+	l = 0.2104542553*lc + 0.7936177850*mc - 0.0040720468*sc
+	a = 1.9779984951*lc - 2.4285922050*mc + 0.4505937099*sc
+	b = 0.0259040371*lc + 0.7827717662*mc - 0.8086757660*sc
+	return
+}
+
+// OkLabColor - Color from OKLab coordinates. The result may be out of the sRGB gamut.
+func OkLabColor(l, a, b float64) colorful.Color {
+	r, g, bl := okLabToLinear(l, a, b)
+	return colorful.LinearRgb(r, g, bl)
+}
+
+// OkLch - Color in the polar form of OKLab. Hue is in degrees [0, 360).
+func OkLch(c colorful.Color) (l, chroma, h float64) {
+	l, a, b := OkLab(c)
+	chroma = math.Hypot(a, b)
+	h = math.Mod(math.Atan2(b, a)*180/math.Pi+360, 360)
+	return
+}
+
+// OkLchColor - Color from OKLCh coordinates. The result may be out of the sRGB gamut.
+func OkLchColor(l, chroma, h float64) colorful.Color {
+	h *= math.Pi / 180
+	return OkLabColor(l, chroma*math.Cos(h), chroma*math.Sin(h))
+}
+
+// okLabToLinear - OKLab to linear sRGB
+func okLabToLinear(l, a, b float64) (float64, float64, float64) {
+	lc := l + 0.3963377774*a + 0.2158037573*b
+	mc := l - 0.1055613458*a - 0.0638541728*b
+	sc := l - 0.0894841775*a - 1.2914855480*b
+	lc, mc, sc = lc*lc*lc, mc*mc*mc, sc*sc*sc
+	return 4.0767416621*lc - 3.3077115913*mc + 0.2309699292*sc,
+		-1.2684380046*lc + 2.6097574011*mc - 0.3413193965*sc,
+		-0.0041960863*lc - 0.7034186147*mc + 1.7076147010*sc
+}
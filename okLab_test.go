@@ -0,0 +1,47 @@
+package imagecolor
+
+import (
+	"math"
+	"testing"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+func TestOkLab(t *testing.T) {
+	// Reference values from Björn Ottosson's OKLab post
+	tests := []struct {
+		c       colorful.Color
+		l, a, b float64
+	}{
+		{colorful.Color{R: 1, G: 1, B: 1}, 1, 0, 0},
+		{colorful.Color{R: 1}, 0.627955, 0.224863, 0.125846},
+		{colorful.Color{G: 1}, 0.866440, -0.233888, 0.179498},
+		{colorful.Color{B: 1}, 0.452014, -0.032457, -0.311528},
+	}
+	for _, tt := range tests {
+		l, a, b := OkLab(tt.c)
+		if math.Abs(l-tt.l) > 1e-4 || math.Abs(a-tt.a) > 1e-4 || math.Abs(b-tt.b) > 1e-4 {
+			t.Errorf("OkLab of %v was incorrect, got: %v %v %v, want: %v %v %v.", tt.c.Hex(), l, a, b, tt.l, tt.a, tt.b)
+		}
+		if c := OkLabColor(l, a, b); c.DistanceRgb(tt.c) > 1e-5 {
+			t.Errorf("OkLabColor was incorrect, got: %v, want: %v.", c, tt.c)
+		}
+	}
+}
+
+func TestOkLch(t *testing.T) {
+	for _, hex := range []string{"#f44336", "#2196f3", "#4caf50", "#ffeb3b", "#9c27b0", "#795548", "#808080"} {
+		c, _ := colorful.Hex(hex)
+		l, chroma, h := OkLch(c)
+		if h < 0 || h >= 360 || chroma < 0 {
+			t.Errorf("OkLch of %v was incorrect, got: %v %v %v, want: hue in [0, 360).", hex, l, chroma, h)
+		}
+		if rt := OkLchColor(l, chroma, h); rt.DistanceRgb(c) > 1e-5 {
+			t.Errorf("OkLch round trip of %v was incorrect, got: %v, want: %v.", hex, rt.Hex(), hex)
+		}
+	}
+	// Grays have no chroma
+	if _, chroma, _ := OkLch(colorful.Color{R: 0.3, G: 0.3, B: 0.3}); chroma > 1e-6 {
+		t.Errorf("Gray chroma was incorrect, got: %v, want: %v.", chroma, 0)
+	}
+}
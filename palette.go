@@ -0,0 +1,276 @@
+package imagecolor
+
+import (
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+// Palette Defaults
+const (
+	cubeSize             = 32   // cells per RGB axis
+	cubeBoundary         = 0xff // cell that needs refinement
+	cubeHueMargin        = 0.5  // degrees
+	cubeSaturationMargin = 0.01
+)
+
+// materialPalette - Palette of every Material color series, used by closestMaterialColor
+var materialPalette = NewMaterialPalette()
+
+// paletteEntry - Color of a Palette and its MaterialColor class
+type paletteEntry struct {
+	class MaterialColor
+	color ColorHSL
+}
+
+// Palette - Classifies colors to the nearest MaterialColor of one or more Material color series.
+// Entries are kept in a fixed order (series ascending, then MaterialColor) so that ties are
+// always resolved the same way.
+//
+// Classification uses a 32x32x32 RGB lookup cube built once per Palette. Cells that only
+// contain colors of a single class are a table lookup. Cells on a class boundary keep the
+// few entries that can be the nearest to one of their colors and are refined exactly.
+// Cells on the grey axis, where hue is unstable, scan every entry.
+// A Palette is safe for concurrent use.
+type Palette struct {
+	entries []paletteEntry
+
+	once sync.Once
+	cube []uint8
+	// candidates of boundary cell i are candidates[offsets[i]:offsets[i+1]]
+	offsets    []uint32
+	candidates []uint8
+}
+
+// NewMaterialPalette - Palette of the given Material color series (100, 300, 500, 700 or 900).
+// All series are used when none are given; unknown series are ignored.
+func NewMaterialPalette(series ...int) *Palette {
+	if len(series) == 0 {
+		for s := range materialColorsSeries {
+			series = append(series, s)
+		}
+	}
+	series = append([]int(nil), series...)
+	sort.Ints(series)
+	p := &Palette{}
+	for i, s := range series {
+		if i > 0 && s == series[i-1] {
+			continue
+		}
+		colors := materialColorsSeries[s]
+		for mc := materialRed; mc <= materialBlack; mc++ {
+			if c, ok := colors[mc]; ok {
+				p.entries = append(p.entries, paletteEntry{class: mc, color: c})
+			}
+		}
+	}
+	return p
+}
+
+// Classify - MaterialColor closest to c.
+// Black, White and Grey are detected first, other colors are looked up in the RGB cube.
+func (p *Palette) Classify(c ColorHSL) MaterialColor {
+	if mc, ok := achromaticClass(c); ok {
+		return mc
+	}
+	return p.lookup(c.Colorful(), c)
+}
+
+// ClassifyColor - MaterialColor closest to the colorful.Color c. See Classify.
+func (p *Palette) ClassifyColor(c colorful.Color) MaterialColor {
+	hsl := NewColorHSL(c)
+	if mc, ok := achromaticClass(hsl); ok {
+		return mc
+	}
+	return p.lookup(c, hsl)
+}
+
+// ClassifyExact - MaterialColor closest to c using a linear scan of the Palette
+func (p *Palette) ClassifyExact(c ColorHSL) MaterialColor {
+	if mc, ok := achromaticClass(c); ok {
+		return mc
+	}
+	return p.nearest(c, nil)
+}
+
+// lookup - Class of the cube cell of rgb, refined with hsl for boundary cells
+func (p *Palette) lookup(rgb colorful.Color, hsl ColorHSL) MaterialColor {
+	p.once.Do(p.buildCube)
+	i := cubeIndex(cubeCell(rgb.R), cubeCell(rgb.G), cubeCell(rgb.B))
+	if mc := p.cube[i]; mc != cubeBoundary {
+		return MaterialColor(mc)
+	}
+	return p.nearest(hsl, p.candidates[p.offsets[i]:p.offsets[i+1]])
+}
+
+// nearest - Palette entry closest to c (ColorHSL.Distance), first entry wins ties.
+// Only the entries listed in candidates are compared, or all entries when candidates is empty.
+// Squared distances are compared to avoid a square root per entry.
+func (p *Palette) nearest(c ColorHSL, candidates []uint8) (colorName MaterialColor) {
+	minDist := 200.0 * 200.0
+	compare := func(e paletteEntry) {
+		dh := math.Abs(c[hueValue] - e.color[hueValue])
+		if dh > 180 {
+			dh = 360 - dh
+		}
+		ds := c[saturationValue] - e.color[saturationValue]
+		dl := c[lightValue] - e.color[lightValue]
+		if dist := dh*dh + ds*ds + dl*dl; dist < minDist {
+			minDist = dist
+			colorName = e.class
+		}
+	}
+	if len(candidates) == 0 {
+		for _, e := range p.entries {
+			compare(e)
+		}
+		return colorName
+	}
+	for _, i := range candidates {
+		compare(p.entries[i])
+	}
+	return colorName
+}
+
+// buildCube - Classify every cube cell from the HSL values of its corners
+func (p *Palette) buildCube() {
+	const n = cubeSize + 1
+	corners := make([]ColorHSL, n*n*n)
+	for r := 0; r < n; r++ {
+		for g := 0; g < n; g++ {
+			for b := 0; b < n; b++ {
+				c := colorful.Color{R: float64(r) / cubeSize, G: float64(g) / cubeSize, B: float64(b) / cubeSize}
+				corners[(r*n+g)*n+b] = NewColorHSL(c)
+			}
+		}
+	}
+	p.cube = make([]uint8, cubeSize*cubeSize*cubeSize)
+	p.offsets = make([]uint32, len(p.cube)+1)
+	minDist := make([]float64, len(p.entries))
+	var cell [8]ColorHSL
+	for r := 0; r < cubeSize; r++ {
+		for g := 0; g < cubeSize; g++ {
+			for b := 0; b < cubeSize; b++ {
+				i := cubeIndex(r, g, b)
+				p.cube[i] = cubeBoundary
+				// Cells next to the grey axis are left without candidates
+				if maxInt(r, maxInt(g, b))-minInt(r, minInt(g, b)) > 1 {
+					for k := range cell {
+						cr, cg, cb := r+(k>>2), g+(k>>1&1), b+(k&1)
+						cell[k] = corners[(cr*n+cg)*n+cb]
+					}
+					p.classifyCell(i, cell, minDist)
+				}
+				p.offsets[i+1] = uint32(len(p.candidates))
+			}
+		}
+	}
+}
+
+// classifyCell - Find the entries that can be the nearest to a color of the cell.
+// The cell takes their class when they all share it, otherwise they become its candidates.
+func (p *Palette) classifyCell(i int, cell [8]ColorHSL, minDist []float64) {
+	// Lightness is monotonic in r, g and b so its range is given by the corners.
+	// Hue (relative to the first corner) and saturation ranges are taken from
+	// the corners with a safety margin.
+	var lo, hi ColorHSL
+	ref := cell[0][hueValue]
+	for k, c := range cell {
+		v := ColorHSL{relativeHue(c[hueValue], ref), c[saturationValue], c[lightValue]}
+		for ch := range v {
+			if k == 0 || v[ch] < lo[ch] {
+				lo[ch] = v[ch]
+			}
+			if k == 0 || v[ch] > hi[ch] {
+				hi[ch] = v[ch]
+			}
+		}
+	}
+	lo[hueValue] -= cubeHueMargin
+	hi[hueValue] += cubeHueMargin
+	lo[saturationValue] -= cubeSaturationMargin
+	hi[saturationValue] += cubeSaturationMargin
+
+	bestMax := math.MaxFloat64
+	for j, e := range p.entries {
+		v := ColorHSL{relativeHue(e.color[hueValue], ref), e.color[saturationValue], e.color[lightValue]}
+		var dmin, dmax float64
+		for ch := range v {
+			near, far := intervalDistance(v[ch], lo[ch], hi[ch])
+			if ch == int(hueValue) {
+				// hue wraps around, the distance is at most 180 degrees
+				near, far = math.Min(near, 360-far), math.Min(far, 180)
+			}
+			dmin += near * near
+			dmax += far * far
+		}
+		minDist[j] = dmin
+		bestMax = math.Min(bestMax, dmax)
+	}
+
+	start := len(p.candidates)
+	uniform := true
+	for j, e := range p.entries {
+		if minDist[j] > bestMax {
+			continue
+		}
+		if len(p.candidates) > start && e.class != p.entries[p.candidates[start]].class {
+			uniform = false
+		}
+		p.candidates = append(p.candidates, uint8(j))
+	}
+	if uniform && len(p.candidates) > start {
+		p.cube[i] = uint8(p.entries[p.candidates[start]].class)
+		p.candidates = p.candidates[:start]
+	}
+}
+
+// relativeHue - Hue h relative to ref in the range [-180, 180), both in [0, 360)
+func relativeHue(h, ref float64) float64 {
+	d := h - ref
+	if d >= 180 {
+		return d - 360
+	}
+	if d < -180 {
+		return d + 360
+	}
+	return d
+}
+
+// intervalDistance - Smallest and largest distance from v to the interval [lo, hi]
+func intervalDistance(v, lo, hi float64) (near, far float64) {
+	switch {
+	case v < lo:
+		near = lo - v
+	case v > hi:
+		near = v - hi
+	}
+	return near, math.Max(math.Abs(v-lo), math.Abs(v-hi))
+}
+
+// achromaticClass - Black, White and Grey detection of closestMaterialColor
+func achromaticClass(c ColorHSL) (MaterialColor, bool) {
+	// Check for black pixels
+	if c[lightValue] < 0.05 {
+		return materialBlack, true
+	}
+	// Check for white pixels
+	if c[saturationValue] < 0.018 && c[lightValue] > 0.95 {
+		return materialWhite, true
+	}
+	// Check for grey pixels
+	if c[saturationValue] == 0.0 && c[hueValue] == 0.0 && c[lightValue] > 0.05 && c[lightValue] < 0.95 {
+		return materialGrey, true
+	}
+	return 0, false
+}
+
+func cubeCell(v float64) int {
+	return minInt(maxInt(int(v*cubeSize), 0), cubeSize-1)
+}
+
+func cubeIndex(r, g, b int) int {
+	return (r*cubeSize+g)*cubeSize + b
+}
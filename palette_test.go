@@ -0,0 +1,36 @@
+package imagecolor
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+func TestPaletteClassify(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for _, p := range []*Palette{NewMaterialPalette(), NewMaterialPalette(500), NewMaterialPalette(100, 900)} {
+		for i := 0; i < 200000; i++ {
+			c := NewColorHSL(colorful.Color{R: rnd.Float64(), G: rnd.Float64(), B: rnd.Float64()})
+			if got, want := p.Classify(c), p.ClassifyExact(c); got != want {
+				t.Errorf("Classify(%v) was incorrect, got: %v, want: %v.", c, got, want)
+				return
+			}
+		}
+	}
+}
+
+func BenchmarkClassify(b *testing.B) {
+	c := NewColorHSL(colorful.Color{R: 0.8, G: 0.3, B: 0.25})
+	materialPalette.Classify(c)
+	b.Run("Cube", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			materialPalette.Classify(c)
+		}
+	})
+	b.Run("Exact", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			materialPalette.ClassifyExact(c)
+		}
+	})
+}
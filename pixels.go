@@ -0,0 +1,279 @@
+package imagecolor
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/evanoberholster/imageColor/hdr"
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+// pixelReader - Returns a function that reads the pixel at x, y as a colorful.Color.
+// 16 bit and float32 (hdr.RGBA) images are read directly from their pixel buffers so that
+// no precision is lost to 16 bit alpha-premultiplication. Float32 values are clamped to [0, 1].
+// 8 bit RGBA, NRGBA, YCbCr and Gray images avoid allocating a color.Color per pixel.
+// Other images are read through color.Color.RGBA().
+func pixelReader(m image.Image) func(x, y int) colorful.Color {
+	switch img := m.(type) {
+	case *hdr.RGBA:
+		return func(x, y int) colorful.Color {
+			c := img.RGBAAt(x, y)
+			a := clamp01(float64(c.A))
+			return colorful.Color{
+				R: clamp01(float64(c.R)) * a,
+				G: clamp01(float64(c.G)) * a,
+				B: clamp01(float64(c.B)) * a,
+			}
+		}
+	case *image.NRGBA64:
+		return func(x, y int) colorful.Color {
+			i := img.PixOffset(x, y)
+			s := img.Pix[i : i+8 : i+8]
+			a := float64(uint16(s[6])<<8|uint16(s[7])) / 65535.0
+			return colorful.Color{
+				R: float64(uint16(s[0])<<8|uint16(s[1])) / 65535.0 * a,
+				G: float64(uint16(s[2])<<8|uint16(s[3])) / 65535.0 * a,
+				B: float64(uint16(s[4])<<8|uint16(s[5])) / 65535.0 * a,
+			}
+		}
+	case *image.RGBA64:
+		return func(x, y int) colorful.Color {
+			i := img.PixOffset(x, y)
+			s := img.Pix[i : i+6 : i+6]
+			return colorful.Color{
+				R: float64(uint16(s[0])<<8|uint16(s[1])) / 65535.0,
+				G: float64(uint16(s[2])<<8|uint16(s[3])) / 65535.0,
+				B: float64(uint16(s[4])<<8|uint16(s[5])) / 65535.0,
+			}
+		}
+	case *image.Gray16:
+		return func(x, y int) colorful.Color {
+			i := img.PixOffset(x, y)
+			v := float64(uint16(img.Pix[i])<<8|uint16(img.Pix[i+1])) / 65535.0
+			return colorful.Color{R: v, G: v, B: v}
+		}
+	case *image.RGBA:
+		return func(x, y int) colorful.Color {
+			return newColorful(img.RGBAAt(x, y).RGBA())
+		}
+	case *image.NRGBA:
+		return func(x, y int) colorful.Color {
+			return newColorful(img.NRGBAAt(x, y).RGBA())
+		}
+	case *image.YCbCr:
+		return func(x, y int) colorful.Color {
+			return newColorful(img.YCbCrAt(x, y).RGBA())
+		}
+	case *image.Gray:
+		return func(x, y int) colorful.Color {
+			return newColorful(img.GrayAt(x, y).RGBA())
+		}
+	}
+	return func(x, y int) colorful.Color {
+		return newColorful(m.At(x, y).RGBA())
+	}
+}
+
+// hslReader - Returns a function that reads the pixel at x, y as a ColorHSL.
+// 8 and 16 bit RGBA, NRGBA, YCbCr and Gray images are converted from their integer values
+// with hslFromRGB, other images through pixelReader and colorful.Color.Hsl().
+func hslReader(m image.Image) func(x, y int) ColorHSL {
+	switch img := m.(type) {
+	case *image.RGBA:
+		return func(x, y int) ColorHSL {
+			i := img.PixOffset(x, y)
+			s := img.Pix[i : i+3 : i+3]
+			return hslFromRGB(uint32(s[0]), uint32(s[1]), uint32(s[2]), 0xff)
+		}
+	case *image.NRGBA:
+		return func(x, y int) ColorHSL {
+			i := img.PixOffset(x, y)
+			s := img.Pix[i : i+4 : i+4]
+			if s[3] == 0xff {
+				return hslFromRGB(uint32(s[0]), uint32(s[1]), uint32(s[2]), 0xff)
+			}
+			r, g, b, _ := color.NRGBA{s[0], s[1], s[2], s[3]}.RGBA()
+			return hslFromRGB(r, g, b, 0xffff)
+		}
+	case *image.YCbCr:
+		return func(x, y int) ColorHSL {
+			r, g, b, _ := img.YCbCrAt(x, y).RGBA()
+			return hslFromRGB(r, g, b, 0xffff)
+		}
+	case *image.Gray:
+		return func(x, y int) ColorHSL {
+			return ColorHSL{0, 0, float64(img.Pix[img.PixOffset(x, y)]) / 0xff}
+		}
+	case *image.RGBA64:
+		return func(x, y int) ColorHSL {
+			i := img.PixOffset(x, y)
+			s := img.Pix[i : i+6 : i+6]
+			return hslFromRGB(uint32(s[0])<<8|uint32(s[1]), uint32(s[2])<<8|uint32(s[3]), uint32(s[4])<<8|uint32(s[5]), 0xffff)
+		}
+	case *image.Gray16:
+		return func(x, y int) ColorHSL {
+			i := img.PixOffset(x, y)
+			return ColorHSL{0, 0, float64(uint32(img.Pix[i])<<8|uint32(img.Pix[i+1])) / 0xffff}
+		}
+	}
+	pixel := pixelReader(m)
+	return func(x, y int) ColorHSL {
+		return NewColorHSL(pixel(x, y))
+	}
+}
+
+// hslRow8 tables - Lightness of the sum of the largest and smallest channel, reciprocals of the
+// saturation denominators and the hue scale of the channel difference of 8 bit colors
+var (
+	lightness8 [2*0xff + 1]float64
+	recip8     [2*0xff + 1]float64
+	hueScale8  [0xff + 1]float64
+)
+
+func init() {
+	for n := range lightness8 {
+		lightness8[n] = float64(n) / (2 * 0xff)
+		if n > 0 {
+			recip8[n] = 1 / float64(n)
+		}
+	}
+	for d := 1; d < len(hueScale8); d++ {
+		hueScale8[d] = 60 / float64(d)
+	}
+}
+
+// fillTile - Width in pixels of the tiles read by fillHSL: 16 RGBA pixels are a 64 byte cache line
+const fillTile = 16
+
+// fillHSL - Fill the columns of ic with the ColorHSL of the pixels of m.
+// 8 bit RGBA and NRGBA images are converted a row of fillTile pixels at a time by
+// hslRow8, so that Pix and the columns of ic are read and written in order.
+// Other images are read through hslReader.
+func fillHSL(ic ImageColors, m image.Image) {
+	b := m.Bounds()
+	var pix []uint8
+	var stride int
+	var nrgba bool
+	switch img := m.(type) {
+	case *image.RGBA:
+		pix, stride = img.Pix[img.PixOffset(b.Min.X, b.Min.Y):], img.Stride
+	case *image.NRGBA:
+		pix, stride, nrgba = img.Pix[img.PixOffset(b.Min.X, b.Min.Y):], img.Stride, true
+	default:
+		pixel := hslReader(m)
+		for x, col := range ic {
+			for y := range col {
+				col[y] = pixel(b.Min.X+x, b.Min.Y+y)
+			}
+		}
+		return
+	}
+	for x0 := 0; x0 < len(ic); x0 += fillTile {
+		cols := ic[x0:minInt(x0+fillTile, len(ic))]
+		for y := 0; y < b.Dy(); y++ {
+			i := y*stride + 4*x0
+			hslRow8(cols, y, pix[i:i+4*len(cols):i+4*len(cols)], nrgba)
+		}
+	}
+}
+
+// hslRow8 - Set row y of cols to the ColorHSL of the 8 bit RGBA (NRGBA when nrgba) pixels
+// of row. This is hslFromRGB(r, g, b, 0xff) with the divisions replaced by multiplications
+// with the tables lightness8, recip8 and hueScale8, written out in the loop because a call
+// per pixel costs as much as the conversion. A rounded reciprocal adds at most 1 ulp, so
+// results agree with colorful.Color.Hsl() to within 1e-12 (hue within 1e-9 degrees).
+// Translucent NRGBA pixels are premultiplied and converted with hslFromRGB.
+func hslRow8(cols ImageColors, y int, row []uint8, nrgba bool) {
+	for x, col := range cols {
+		s := row[4*x : 4*x+4 : 4*x+4]
+		r, g, b := s[0], s[1], s[2]
+		if nrgba && s[3] != 0xff {
+			r, g, b, _ := color.NRGBA{r, g, b, s[3]}.RGBA()
+			col[y] = hslFromRGB(r, g, b, 0xffff)
+			continue
+		}
+		max, min := r, r
+		if g > max {
+			max = g
+		}
+		if b > max {
+			max = b
+		}
+		if g < min {
+			min = g
+		}
+		if b < min {
+			min = b
+		}
+		sum := int(max) + int(min)
+		if max == min {
+			col[y] = ColorHSL{0, 0, lightness8[sum]}
+			continue
+		}
+		d := int(max) - int(min)
+		denom := sum
+		if sum >= 0xff {
+			denom = 2*0xff - sum
+		}
+		sat := float64(d) * recip8[denom]
+		// Hue sextant selected without branches, negative red hues wrap by 6*d (360 degrees)
+		num, base := int(r)-int(g), 240.0
+		if max == g {
+			num, base = int(b)-int(r), 120
+		}
+		if max == r {
+			num, base = int(g)-int(b), 0
+		}
+		wrap := 0
+		if max == r && g < b {
+			wrap = 6 * d
+		}
+		col[y] = ColorHSL{base + float64(num+wrap)*hueScale8[d], sat, lightness8[sum]}
+	}
+}
+
+// hslFromRGB - ColorHSL of integer r, g and b in the range [0, full].
+// Differences and sums are computed exactly with integers so every component is rounded
+// once; results agree with colorful.Color.Hsl() to within 1e-12 (hue within 1e-9 degrees).
+// Classification only differs when a color is exactly on a class boundary.
+func hslFromRGB(r, g, b, full uint32) ColorHSL {
+	max, min := r, r
+	if g > max {
+		max = g
+	}
+	if b > max {
+		max = b
+	}
+	if g < min {
+		min = g
+	}
+	if b < min {
+		min = b
+	}
+	sum := max + min
+	l := float64(sum) / float64(2*full)
+	if max == min {
+		return ColorHSL{0, 0, l}
+	}
+	d := float64(max - min)
+	var s float64
+	if sum < full {
+		s = d / float64(sum)
+	} else {
+		s = d / float64(2*full-sum)
+	}
+	var h float64
+	switch max {
+	case r:
+		h = float64(int32(g)-int32(b)) / d
+	case g:
+		h = 2 + float64(int32(b)-int32(r))/d
+	default:
+		h = 4 + float64(int32(r)-int32(g))/d
+	}
+	h *= 60
+	if h < 0 {
+		h += 360
+	}
+	return ColorHSL{h, s, l}
+}
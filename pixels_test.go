@@ -0,0 +1,162 @@
+package imagecolor
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"math"
+	"math/rand"
+	"os"
+	"testing"
+
+	"github.com/evanoberholster/imageColor/hdr"
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+func TestHSLFromRGB(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 500000; i++ {
+		r, g, b := uint32(rnd.Intn(256)), uint32(rnd.Intn(256)), uint32(rnd.Intn(256))
+		if i%2 == 1 {
+			r, g, b = uint32(rnd.Intn(0x10000)), uint32(rnd.Intn(0x10000)), uint32(rnd.Intn(0x10000))
+		}
+		full := uint32(0xff)
+		if i%2 == 1 {
+			full = 0xffff
+		}
+		got := hslFromRGB(r, g, b, full)
+		want := NewColorHSL(newColorful(r*0xffff/full, g*0xffff/full, b*0xffff/full, 0xffff))
+		if math.Abs(got[hueValue]-want[hueValue]) > 1e-9 ||
+			math.Abs(got[saturationValue]-want[saturationValue]) > 1e-12 ||
+			math.Abs(got[lightValue]-want[lightValue]) > 1e-12 {
+			t.Errorf("hslFromRGB(%d, %d, %d) was incorrect, got: %v, want: %v.", r, g, b, got, want)
+			return
+		}
+		if closestMaterialColor(got) != closestMaterialColor(want) {
+			t.Errorf("Classification of (%d, %d, %d) was incorrect, got: %v, want: %v.", r, g, b, closestMaterialColor(got), closestMaterialColor(want))
+			return
+		}
+	}
+}
+
+func TestFillHSL8(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	rect := image.Rect(3, 1, 40, 300) // not a multiple of fillTile wide
+	rgba, nrgba := image.NewRGBA(rect), image.NewNRGBA(rect)
+	rnd.Read(rgba.Pix)
+	rnd.Read(nrgba.Pix)
+	for i := 3; i < len(rgba.Pix); i += 4 {
+		rgba.Pix[i] = 0xff
+		if i%8 == 3 {
+			nrgba.Pix[i] = 0xff
+		}
+	}
+	// Grays and every hue sextant
+	for x, c := range []color.RGBA{{0, 0, 0, 0xff}, {0x80, 0x80, 0x80, 0xff}, {0xff, 0, 0x01, 0xff}, {0xff, 0x01, 0, 0xff}, {0, 0xff, 0, 0xff}, {0, 0, 0xff, 0xff}} {
+		rgba.SetRGBA(rect.Min.X+x, rect.Min.Y, c)
+	}
+	for _, m := range []image.Image{rgba, nrgba} {
+		ic := GetImageColors(m)
+		for x := 0; x < rect.Dx(); x++ {
+			for y := 0; y < rect.Dy(); y++ {
+				got := (*ic)[x][y]
+				r, g, b, _ := m.At(rect.Min.X+x, rect.Min.Y+y).RGBA()
+				want := hslFromRGB(r, g, b, 0xffff)
+				if math.Abs(got[hueValue]-want[hueValue]) > 1e-9 ||
+					math.Abs(got[saturationValue]-want[saturationValue]) > 1e-12 ||
+					math.Abs(got[lightValue]-want[lightValue]) > 1e-12 {
+					t.Fatalf("%T pixel %d, %d was incorrect, got: %v, want: %v.", m, x, y, got, want)
+				}
+				if closestMaterialColor(got) != closestMaterialColor(want) {
+					t.Fatalf("%T classification of %d, %d was incorrect, got: %v, want: %v.", m, x, y, closestMaterialColor(got), closestMaterialColor(want))
+				}
+			}
+		}
+	}
+}
+
+func TestPixelReader16(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	rect := image.Rect(1, 2, 9, 6)
+	nrgba64, rgba64, gray16 := image.NewNRGBA64(rect), image.NewRGBA64(rect), image.NewGray16(rect)
+	rnd.Read(nrgba64.Pix)
+	rnd.Read(gray16.Pix)
+	// RGBA64 holds premultiplied colors
+	draw.Draw(rgba64, rect, nrgba64, rect.Min, draw.Src)
+	float := hdr.Convert(nrgba64)
+	for _, m := range []image.Image{nrgba64, rgba64, gray16, float} {
+		pixel, hsl := pixelReader(m), hslReader(m)
+		for y := rect.Min.Y; y < rect.Max.Y; y++ {
+			for x := rect.Min.X; x < rect.Max.X; x++ {
+				got, want := pixel(x, y), newColorful(m.At(x, y).RGBA())
+				if math.Abs(got.R-want.R) > 1.0/0xffff || math.Abs(got.G-want.G) > 1.0/0xffff || math.Abs(got.B-want.B) > 1.0/0xffff {
+					t.Errorf("%T pixel at %d, %d was incorrect, got: %v, want: %v.", m, x, y, got, want)
+				}
+				if h, w := hsl(x, y), NewColorHSL(got); math.Abs(h[saturationValue]-w[saturationValue]) > 1e-9 || math.Abs(h[lightValue]-w[lightValue]) > 1e-9 {
+					t.Errorf("%T HSL at %d, %d was incorrect, got: %v, want: %v.", m, x, y, h, w)
+				}
+			}
+		}
+	}
+
+	// 16 bit values are not truncated to 8 bits
+	gray16.SetGray16(1, 2, color.Gray16{Y: 0x8000})
+	gray16.SetGray16(2, 2, color.Gray16{Y: 0x80ff})
+	if a, b := pixelReader(gray16)(1, 2), pixelReader(gray16)(2, 2); a == b || a.R != float64(0x8000)/0xffff {
+		t.Errorf("Gray16 pixels was incorrect, got: %v %v, want: %v %v.", a.R, b.R, float64(0x8000)/0xffff, float64(0x80ff)/0xffff)
+	}
+
+	// Float values are clamped and premultiplied
+	hdrImage := hdr.NewRGBA(image.Rect(0, 0, 1, 1))
+	hdrImage.SetRGBA(0, 0, hdr.Color{R: 2, G: -1, B: 0.5, A: 0.5})
+	if got := pixelReader(hdrImage)(0, 0); got != (colorful.Color{R: 0.5, G: 0, B: 0.25}) {
+		t.Errorf("hdr pixel was incorrect, got: %v, want: %v.", got, colorful.Color{R: 0.5, G: 0, B: 0.25})
+	}
+}
+
+func benchmarkImage() *image.RGBA {
+	rnd := rand.New(rand.NewSource(1))
+	m := image.NewRGBA(image.Rect(0, 0, 512, 512))
+	rnd.Read(m.Pix)
+	for i := 3; i < len(m.Pix); i += 4 {
+		m.Pix[i] = 0xff
+	}
+	return m
+}
+
+func BenchmarkGetImageColors(b *testing.B) {
+	m := benchmarkImage()
+	b.SetBytes(int64(len(m.Pix) / 4)) // MB/s reads as megapixels per second
+	for i := 0; i < b.N; i++ {
+		GetImageColors(m)
+	}
+}
+
+func BenchmarkAnalyzerImageColors(b *testing.B) {
+	m := benchmarkImage()
+	a := NewAnalyzer()
+	b.SetBytes(int64(len(m.Pix) / 4))
+	for i := 0; i < b.N; i++ {
+		a.ImageColors(m)
+	}
+}
+
+func BenchmarkAnalyzerImageColorsPhoto(b *testing.B) {
+	f, err := os.Open("hash/tests/test3.jpg")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer f.Close()
+	src, err := jpeg.Decode(f)
+	if err != nil {
+		b.Fatal(err)
+	}
+	m := image.NewRGBA(src.Bounds())
+	draw.Draw(m, m.Bounds(), src, src.Bounds().Min, draw.Src)
+	a := NewAnalyzer()
+	b.SetBytes(int64(len(m.Pix) / 4))
+	for i := 0; i < b.N; i++ {
+		a.ImageColors(m)
+	}
+}
@@ -0,0 +1,239 @@
+package imagecolor
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"sort"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+// MaterialSeriesPalette - color.Palette of a Material color series (100, 300, 500, 700 or 900)
+// ordered by MaterialColor and followed by White and Black. Returns nil for an unknown series.
+func MaterialSeriesPalette(series int) color.Palette {
+	colors, ok := materialColorsSeries[series]
+	if !ok {
+		return nil
+	}
+	p := make(color.Palette, 0, len(colors)+2)
+	for mc := materialRed; mc < materialWhite; mc++ {
+		if c, ok := colors[mc]; ok {
+			p = append(p, colorful.Hsl(c[hueValue], c[saturationValue], c[lightValue]).Clamped())
+		}
+	}
+	return append(p, materialWhite.Colorful(), materialBlack.Colorful())
+}
+
+// Quantizer - draw.Quantizer that builds a palette from the prominent colors of an image.
+// It can be used as gif.Options.Quantizer.
+type Quantizer struct {
+	// Palette restricts the result to the colors of a fixed palette (ex: MaterialColorPalette()),
+	// most used first. When nil the mean colors of the MaterialColor classes of the image are used.
+	Palette color.Palette
+	// Averaging is used to compute the mean color of every MaterialColor class.
+	Averaging Averaging
+}
+
+// Quantize - Append up to cap(p)-len(p) colors of m to p, most prominent first
+func (q Quantizer) Quantize(p color.Palette, m image.Image) color.Palette {
+	n := cap(p) - len(p)
+	if n <= 0 {
+		return p
+	}
+	if q.Palette != nil {
+		return append(p, q.usedColors(m, n)...)
+	}
+
+	ic := GetImageColors(m)
+	cm := ic.ClassMap()
+	centroids := cm.Centroids(*ic, q.Averaging)
+	weights := cm.Weights()
+	classes := make([]MaterialColor, 0, len(centroids))
+	for mc := range centroids {
+		classes = append(classes, mc)
+	}
+	sort.Slice(classes, func(i, j int) bool {
+		if weights[classes[i]] == weights[classes[j]] {
+			return classes[i] < classes[j]
+		}
+		return weights[classes[i]] > weights[classes[j]]
+	})
+	for i := 0; i < len(classes) && i < n; i++ {
+		p = append(p, centroids[classes[i]].Colorful().Clamped())
+	}
+	return p
+}
+
+// usedColors - Up to n colors of q.Palette, ordered by the number of pixels of m closest to them
+func (q Quantizer) usedColors(m image.Image, n int) color.Palette {
+	counts := make([]int, len(q.Palette))
+	bounds := m.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			counts[q.Palette.Index(m.At(x, y))]++
+		}
+	}
+	order := make([]int, len(q.Palette))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return counts[order[i]] > counts[order[j]] })
+	var res color.Palette
+	for _, i := range order {
+		if counts[i] == 0 || len(res) == n {
+			break
+		}
+		res = append(res, q.Palette[i])
+	}
+	return res
+}
+
+// Dither - Dithering method of a Drawer
+type Dither uint8
+
+// Dithering Methods
+const (
+	DitherNone Dither = iota
+	DitherFloydSteinberg
+	DitherAtkinson
+	DitherBayer
+)
+
+// diffusion - Error diffusion kernel entry
+type diffusion struct {
+	dx, dy int
+	w      float64
+}
+
+var (
+	floydSteinbergKernel = []diffusion{{1, 0, 7.0 / 16}, {-1, 1, 3.0 / 16}, {0, 1, 5.0 / 16}, {1, 1, 1.0 / 16}}
+	// Atkinson diffuses only 3/4 of the error
+	atkinsonKernel = []diffusion{{1, 0, 1.0 / 8}, {2, 0, 1.0 / 8}, {-1, 1, 1.0 / 8}, {0, 1, 1.0 / 8}, {1, 1, 1.0 / 8}, {0, 2, 1.0 / 8}}
+
+	// bayerMatrix - 8x8 ordered dithering thresholds
+	bayerMatrix = [8][8]float64{
+		{0, 32, 8, 40, 2, 34, 10, 42},
+		{48, 16, 56, 24, 50, 18, 58, 26},
+		{12, 44, 4, 36, 14, 46, 6, 38},
+		{60, 28, 52, 20, 62, 30, 54, 22},
+		{3, 35, 11, 43, 1, 33, 9, 41},
+		{51, 19, 59, 27, 49, 17, 57, 25},
+		{15, 47, 7, 39, 13, 45, 5, 37},
+		{63, 31, 55, 23, 61, 29, 53, 21},
+	}
+)
+
+// Drawer - draw.Drawer that maps images onto a palette with optional dithering.
+// It can be used as gif.Options.Drawer.
+type Drawer struct {
+	Dither Dither
+	// Palette is used when the destination is not an *image.Paletted.
+	// When nil MaterialColorPalette() is used.
+	Palette color.Palette
+}
+
+// Draw - Draw src onto the palette of dst. Implements draw.Drawer
+func (d Drawer) Draw(dst draw.Image, r image.Rectangle, src image.Image, sp image.Point) {
+	orig := r.Min
+	r = r.Intersect(dst.Bounds())
+	r = r.Intersect(src.Bounds().Add(orig.Sub(sp)))
+	if r.Empty() {
+		return
+	}
+	sp = sp.Add(r.Min.Sub(orig))
+
+	paletted, _ := dst.(*image.Paletted)
+	pal := d.Palette
+	if paletted != nil {
+		pal = paletted.Palette
+	}
+	if len(pal) == 0 {
+		pal = MaterialColorPalette()
+	}
+	pc := make([][4]float64, len(pal))
+	for i, c := range pal {
+		pc[i] = rgbaFloats(c)
+	}
+	set := func(x, y, i int) {
+		if paletted != nil {
+			paletted.SetColorIndex(x, y, uint8(i))
+			return
+		}
+		dst.Set(x, y, pal[i])
+	}
+
+	var kernel []diffusion
+	switch d.Dither {
+	case DitherFloydSteinberg:
+		kernel = floydSteinbergKernel
+	case DitherAtkinson:
+		kernel = atkinsonKernel
+	}
+	// Rows of accumulated error, padded by 2 pixels on each side
+	width := r.Dx()
+	errs := make([][][4]float64, 3)
+	for i := range errs {
+		errs[i] = make([][4]float64, width+4)
+	}
+	// Ordered dithering spreads colors by about the distance between palette levels
+	spread := math.Min(1, 1/(math.Cbrt(float64(len(pal)))-1))
+
+	for y := 0; y < r.Dy(); y++ {
+		for x := 0; x < width; x++ {
+			v := rgbaFloats(src.At(sp.X+x, sp.Y+y))
+			switch {
+			case kernel != nil:
+				for c := range v {
+					v[c] += errs[0][x+2][c]
+				}
+			case d.Dither == DitherBayer:
+				t := (bayerMatrix[(r.Min.Y+y)&7][(r.Min.X+x)&7]+0.5)/64 - 0.5
+				for c := 0; c < 3; c++ {
+					v[c] += t * spread
+				}
+			}
+			// Clamped like image/draw.FloydSteinberg, so error does not build up past saturated colors
+			for c := range v {
+				v[c] = clamp01(v[c])
+			}
+			i := nearestColor(pc, v)
+			set(r.Min.X+x, r.Min.Y+y, i)
+			for _, k := range kernel {
+				e := &errs[k.dy][x+2+k.dx]
+				for c := range v {
+					e[c] += (v[c] - pc[i][c]) * k.w
+				}
+			}
+		}
+		if kernel != nil {
+			errs[0], errs[1], errs[2] = errs[1], errs[2], errs[0]
+			for i := range errs[2] {
+				errs[2][i] = [4]float64{}
+			}
+		}
+	}
+}
+
+// rgbaFloats - Alpha-premultiplied color in the range [0, 1]
+func rgbaFloats(c color.Color) [4]float64 {
+	r, g, b, a := c.RGBA()
+	return [4]float64{float64(r) / 0xffff, float64(g) / 0xffff, float64(b) / 0xffff, float64(a) / 0xffff}
+}
+
+// nearestColor - Index of the palette color closest to v (squared euclidean distance)
+func nearestColor(pc [][4]float64, v [4]float64) int {
+	best, bestDist := 0, math.MaxFloat64
+	for i, p := range pc {
+		var dist float64
+		for c := range v {
+			d := v[c] - p[c]
+			dist += d * d
+		}
+		if dist < bestDist {
+			best, bestDist = i, dist
+		}
+	}
+	return best
+}
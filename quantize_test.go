@@ -0,0 +1,141 @@
+package imagecolor
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"math"
+	"testing"
+)
+
+func TestMaterialSeriesPalette(t *testing.T) {
+	for _, series := range []int{100, 300, 500, 700, 900} {
+		p := MaterialSeriesPalette(series)
+		if len(p) != len(materialColorsSeries[series])+2 {
+			t.Errorf("Series %d length was incorrect, got: %v, want: %v.", series, len(p), len(materialColorsSeries[series])+2)
+		}
+		if p[len(p)-2] != materialWhite.Colorful() || p[len(p)-1] != materialBlack.Colorful() {
+			t.Errorf("Series %d was incorrect, got: %v %v, want: white and black last.", series, p[len(p)-2], p[len(p)-1])
+		}
+	}
+	if p := MaterialSeriesPalette(400); p != nil {
+		t.Errorf("Unknown series was incorrect, got: %v, want: nil.", p)
+	}
+}
+
+func TestQuantizer(t *testing.T) {
+	m := image.NewRGBA(image.Rect(0, 0, 30, 10))
+	for x := 0; x < 30; x++ {
+		for y := 0; y < 10; y++ {
+			c := testRed
+			if x >= 20 {
+				c = testBlue
+			}
+			m.SetRGBA(x, y, c)
+		}
+	}
+
+	p := Quantizer{}.Quantize(make(color.Palette, 0, 4), m)
+	if len(p) != 2 || rgba8(p[0]) != testRed || rgba8(p[1]) != testBlue {
+		t.Errorf("Quantize was incorrect, got: %v, want: %v.", p, []color.RGBA{testRed, testBlue})
+	}
+	if p := (Quantizer{}).Quantize(make(color.Palette, 0, 1), m); len(p) != 1 || rgba8(p[0]) != testRed {
+		t.Errorf("Quantize of 1 color was incorrect, got: %v, want: %v.", p, testRed)
+	}
+	full := color.Palette{color.Black}
+	if p := (Quantizer{}).Quantize(full, m); len(p) != 1 {
+		t.Errorf("Quantize of a full palette was incorrect, got: %v, want: %v.", p, full)
+	}
+
+	// With a fixed palette every color is a member of it, most used first
+	palette := MaterialSeriesPalette(500)
+	p = Quantizer{Palette: palette}.Quantize(make(color.Palette, 1, 8), m)
+	if len(p) != 3 || p[1] != palette[palette.Index(testRed)] || p[2] != palette[palette.Index(testBlue)] {
+		t.Errorf("Quantize with a palette was incorrect, got: %v, want: %v.", p, "empty, red and blue of the palette")
+	}
+}
+
+// rgba8 - 8 bit RGBA of c
+func rgba8(c color.Color) color.RGBA {
+	return color.RGBAModel.Convert(c).(color.RGBA)
+}
+
+func TestDrawer(t *testing.T) {
+	gray := testFill(32, 32, color.RGBA{0x80, 0x80, 0x80, 0xff})
+	bw := color.Palette{color.Black, color.White}
+	tests := []struct {
+		dither Dither
+		white  float64 // fraction of white pixels
+	}{
+		{DitherNone, 1},
+		{DitherFloydSteinberg, 0.5},
+		{DitherAtkinson, 0.5},
+		{DitherBayer, 0.5},
+	}
+	for _, tt := range tests {
+		dst := image.NewPaletted(gray.Bounds(), bw)
+		Drawer{Dither: tt.dither}.Draw(dst, dst.Bounds(), gray, image.Point{})
+		var white float64
+		for _, i := range dst.Pix {
+			white += float64(i) / float64(len(dst.Pix))
+		}
+		// Dithering keeps the mean color of 0x80, slightly above one half
+		if math.Abs(white-tt.white) > 0.05 {
+			t.Errorf("Dither %v was incorrect, got: %v white, want: %v.", tt.dither, white, tt.white)
+		}
+	}
+
+	// Error past saturated colors is clamped like image/draw.FloydSteinberg: a red half
+	// that no palette color matches dithers into the black half the same way
+	edge := image.NewRGBA(image.Rect(0, 0, 32, 16))
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			edge.SetRGBA(x, y, color.RGBA{0xff, 0, 0, 0xff})
+		}
+	}
+	edgePalette := color.Palette{color.Black, color.White, color.RGBA{0, 0, 0xff, 0xff}}
+	got, want := image.NewPaletted(edge.Bounds(), edgePalette), image.NewPaletted(edge.Bounds(), edgePalette)
+	Drawer{Dither: DitherFloydSteinberg}.Draw(got, got.Bounds(), edge, image.Point{})
+	draw.FloydSteinberg.Draw(want, want.Bounds(), edge, image.Point{})
+	if !bytes.Equal(got.Pix, want.Pix) {
+		t.Errorf("Saturated edge was incorrect, got: %v, want: %v.", got.Pix, want.Pix)
+	}
+
+	// Other destinations are drawn with the colors of the Drawer palette
+	palette := MaterialSeriesPalette(700)
+	src := GetImageColors(testFill(8, 8, testRed)).ClassMap().Image()
+	dst := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	Drawer{Dither: DitherFloydSteinberg, Palette: palette}.Draw(dst, image.Rect(2, 2, 20, 20), src, image.Point{})
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 10; x++ {
+			c := dst.RGBAAt(x, y)
+			if x < 2 || y < 2 {
+				if c != (color.RGBA{}) {
+					t.Fatalf("Pixel %v,%v outside the rectangle was incorrect, got: %v, want: %v.", x, y, c, color.RGBA{})
+				}
+				continue
+			}
+			if rgba8(palette[palette.Index(c)]) != c {
+				t.Fatalf("Pixel %v,%v was incorrect, got: %v, want: a palette color.", x, y, c)
+			}
+		}
+	}
+
+	// Quantizer and Drawer plug into gif.Options
+	var buf bytes.Buffer
+	opts := gif.Options{NumColors: 16, Quantizer: Quantizer{Averaging: LinearAveraging}, Drawer: Drawer{Dither: DitherAtkinson}}
+	if err := gif.Encode(&buf, gray, &opts); err != nil {
+		t.Fatal(err)
+	}
+	g, err := gif.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := rgba8(g.At(5, 5)); c != (color.RGBA{0x80, 0x80, 0x80, 0xff}) {
+		t.Errorf("GIF pixel was incorrect, got: %v, want: %v.", c, color.RGBA{0x80, 0x80, 0x80, 0xff})
+	}
+	var _ draw.Drawer = Drawer{}
+	var _ draw.Quantizer = Quantizer{}
+}
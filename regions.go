@@ -0,0 +1,223 @@
+package imagecolor
+
+import (
+	"image"
+	"math"
+	"sort"
+)
+
+// ColorRegion - Connected region of pixels classified as the same MaterialColor
+type ColorRegion struct {
+	Color       MaterialColor
+	Area        int             // number of pixels
+	Centroid    [2]float64      // x, y
+	Bounds      image.Rectangle // bounding box
+	Mean        ColorHSL        // mean color of the region's pixels
+	Compactness float64         // 4*Pi*Area/Perimeter^2, pi/4 for a square and lower for ragged regions
+}
+
+// Regions - Connected components (4-connected) of pixels with the same MaterialColor.
+// ic must be the ImageColors the ColorClassMap was created from.
+// Regions smaller than minArea pixels are dropped. Regions are sorted by Area, largest first.
+func (cm *ColorClassMap) Regions(ic ImageColors, minArea int) []ColorRegion {
+	labels := make([]int32, len(cm.classes))
+	for i := range labels {
+		labels[i] = -1
+	}
+	var regions []ColorRegion
+	var stack []int
+	for start := range cm.classes {
+		if labels[start] >= 0 {
+			continue
+		}
+		label := int32(len(regions))
+		mc := cm.classes[start]
+		acc := colorAccumulator{mode: GammaAveraging}
+		region := ColorRegion{Color: mc}
+		var sumX, sumY float64
+		perimeter := 0
+
+		labels[start] = label
+		stack = append(stack[:0], start)
+		for len(stack) > 0 {
+			i := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			x, y := i%cm.Width, i/cm.Width
+
+			region.Area++
+			sumX += float64(x)
+			sumY += float64(y)
+			region.Bounds = region.Bounds.Union(image.Rect(x, y, x+1, y+1))
+			acc.add(ic[x][y].Colorful(), 1)
+
+			for _, n := range [4][2]int{{x - 1, y}, {x + 1, y}, {x, y - 1}, {x, y + 1}} {
+				if n[0] < 0 || n[1] < 0 || n[0] >= cm.Width || n[1] >= cm.Height {
+					perimeter++
+					continue
+				}
+				j := n[1]*cm.Width + n[0]
+				if cm.classes[j] != mc {
+					perimeter++
+					continue
+				}
+				if labels[j] < 0 {
+					labels[j] = label
+					stack = append(stack, j)
+				}
+			}
+		}
+
+		region.Centroid = [2]float64{sumX / float64(region.Area), sumY / float64(region.Area)}
+		region.Mean = NewColorHSL(acc.mean())
+		region.Compactness = 4 * math.Pi * float64(region.Area) / float64(perimeter*perimeter)
+		regions = append(regions, region)
+	}
+
+	res := regions[:0]
+	for _, r := range regions {
+		if r.Area >= minArea {
+			res = append(res, r)
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool { return res[i].Area > res[j].Area })
+	return res
+}
+
+// SLIC Defaults
+const (
+	slicIterations  = 10
+	slicCompactness = 10.0
+)
+
+// slicCenter - Cluster center of a SLIC superpixel in Lab and image coordinates
+type slicCenter struct {
+	l, a, b, x, y float64
+}
+
+// SuperpixelRegions - Segment ImageColors into approximately n SLIC superpixels,
+// classify each superpixel by its mean color and merge neighbouring superpixels
+// of the same MaterialColor into regions.
+// compactness trades color similarity against spatial proximity (0 uses the default of 10).
+func (ic ImageColors) SuperpixelRegions(n int, compactness float64, minArea int) []ColorRegion {
+	width := len(ic)
+	if width == 0 || len(ic[0]) == 0 || n <= 0 {
+		return nil
+	}
+	height := len(ic[0])
+	if compactness <= 0 {
+		compactness = slicCompactness
+	}
+
+	lab := make([][3]float64, width*height)
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			l, a, b := ic[x][y].Colorful().Lab()
+			lab[y*width+x] = [3]float64{l * 100, a * 100, b * 100}
+		}
+	}
+
+	labels := slic(lab, width, height, n, compactness)
+
+	// Classify each superpixel by its mean color
+	accs := make(map[int32]*colorAccumulator)
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			label := labels[y*width+x]
+			acc, ok := accs[label]
+			if !ok {
+				acc = &colorAccumulator{mode: GammaAveraging}
+				accs[label] = acc
+			}
+			acc.add(ic[x][y].Colorful(), 1)
+		}
+	}
+	classes := make(map[int32]MaterialColor, len(accs))
+	for label, acc := range accs {
+		classes[label] = closestMaterialColor(NewColorHSL(acc.mean()))
+	}
+
+	cm := &ColorClassMap{Width: width, Height: height, classes: make([]MaterialColor, width*height)}
+	for i, label := range labels {
+		cm.classes[i] = classes[label]
+	}
+	return cm.Regions(ic, minArea)
+}
+
+// slic - Simple Linear Iterative Clustering (Achanta et al. 2012).
+// Returns the superpixel label of every pixel (row major).
+func slic(lab [][3]float64, width, height, n int, compactness float64) []int32 {
+	step := math.Sqrt(float64(width*height) / float64(n))
+	if step < 1 {
+		step = 1
+	}
+	// Centers start at half a step, or in the middle of images thinner than half a step
+	var centers []slicCenter
+	for y := math.Min(step/2, float64(height)/2); y < float64(height); y += step {
+		for x := math.Min(step/2, float64(width)/2); x < float64(width); x += step {
+			c := lab[int(y)*width+int(x)]
+			centers = append(centers, slicCenter{c[0], c[1], c[2], x, y})
+		}
+	}
+
+	labels := make([]int32, width*height)
+	dists := make([]float64, width*height)
+	m2 := (compactness / step) * (compactness / step)
+	window := int(math.Ceil(step))
+
+	for iter := 0; iter < slicIterations; iter++ {
+		for i := range dists {
+			dists[i] = math.MaxFloat64
+		}
+		for k, c := range centers {
+			x0, x1 := maxInt(int(c.x)-window, 0), minInt(int(c.x)+window+1, width)
+			y0, y1 := maxInt(int(c.y)-window, 0), minInt(int(c.y)+window+1, height)
+			for y := y0; y < y1; y++ {
+				for x := x0; x < x1; x++ {
+					i := y*width + x
+					p := lab[i]
+					dc := (p[0]-c.l)*(p[0]-c.l) + (p[1]-c.a)*(p[1]-c.a) + (p[2]-c.b)*(p[2]-c.b)
+					ds := (float64(x)-c.x)*(float64(x)-c.x) + (float64(y)-c.y)*(float64(y)-c.y)
+					if d := dc + ds*m2; d < dists[i] {
+						dists[i] = d
+						labels[i] = int32(k)
+					}
+				}
+			}
+		}
+
+		sums := make([]slicCenter, len(centers))
+		counts := make([]float64, len(centers))
+		for i, k := range labels {
+			p := lab[i]
+			s := &sums[k]
+			s.l += p[0]
+			s.a += p[1]
+			s.b += p[2]
+			s.x += float64(i % width)
+			s.y += float64(i / width)
+			counts[k]++
+		}
+		for k := range centers {
+			if counts[k] == 0 {
+				continue
+			}
+			s := sums[k]
+			centers[k] = slicCenter{s.l / counts[k], s.a / counts[k], s.b / counts[k], s.x / counts[k], s.y / counts[k]}
+		}
+	}
+	return labels
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
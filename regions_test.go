@@ -0,0 +1,77 @@
+package imagecolor
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// testHalves - Image with a red left half and a blue right half
+func testHalves(width, height int) *ImageColors {
+	m := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := color.RGBA{0xf4, 0x43, 0x36, 0xff}
+			if x >= width/2 {
+				c = color.RGBA{0x21, 0x96, 0xf3, 0xff}
+			}
+			m.SetRGBA(x, y, c)
+		}
+	}
+	return GetImageColors(m)
+}
+
+func TestRegions(t *testing.T) {
+	ic := testHalves(20, 10)
+	regions := ic.ClassMap().Regions(*ic, 0)
+	if len(regions) != 2 {
+		t.Fatalf("Regions was incorrect, got: %v, want: %v.", len(regions), 2)
+	}
+	want := []ColorRegion{
+		{Color: materialRed, Area: 100, Centroid: [2]float64{4.5, 4.5}, Bounds: image.Rect(0, 0, 10, 10)},
+		{Color: materialBlue, Area: 100, Centroid: [2]float64{14.5, 4.5}, Bounds: image.Rect(10, 0, 20, 10)},
+	}
+	for i, r := range regions {
+		w := want[i]
+		if r.Color != w.Color || r.Area != w.Area || r.Centroid != w.Centroid || r.Bounds != w.Bounds {
+			t.Errorf("Region %d was incorrect, got: %v %v %v %v, want: %v %v %v %v.", i, r.Color, r.Area, r.Centroid, r.Bounds, w.Color, w.Area, w.Centroid, w.Bounds)
+		}
+		if r.Compactness <= 0 || r.Compactness > 1 {
+			t.Errorf("Compactness was incorrect, got: %v, want: in (0, 1].", r.Compactness)
+		}
+	}
+	if regions := ic.ClassMap().Regions(*ic, 101); len(regions) != 0 {
+		t.Errorf("Regions with minArea was incorrect, got: %v, want: %v.", len(regions), 0)
+	}
+}
+
+func TestSuperpixelRegions(t *testing.T) {
+	tests := []struct {
+		width, height, n int
+	}{
+		{40, 30, 12},
+		{100, 4, 4}, // thinner than half a step
+		{4, 100, 4},
+		{1, 1, 4},
+		{3, 2, 100}, // more superpixels than pixels
+	}
+	for _, tt := range tests {
+		ic := testHalves(tt.width, tt.height)
+		regions := ic.SuperpixelRegions(tt.n, 0, 0)
+		area := 0
+		for _, r := range regions {
+			area += r.Area
+		}
+		if len(regions) == 0 || area != tt.width*tt.height {
+			t.Errorf("%dx%d regions was incorrect, got: %d regions covering %d pixels, want: %d pixels.", tt.width, tt.height, len(regions), area, tt.width*tt.height)
+		}
+	}
+
+	regions := testHalves(40, 30).SuperpixelRegions(12, 0, 0)
+	if len(regions) != 2 || regions[0].Area != 600 || regions[1].Area != 600 {
+		t.Errorf("Regions was incorrect, got: %v, want: %v.", regions, "a red and a blue half")
+	}
+	if regions := (ImageColors{}).SuperpixelRegions(4, 0, 0); regions != nil {
+		t.Errorf("Empty regions was incorrect, got: %v, want: %v.", regions, nil)
+	}
+}
@@ -0,0 +1,292 @@
+package imagecolor
+
+import (
+	"image"
+	"math"
+	"math/cmplx"
+	"sort"
+
+	"gonum.org/v1/gonum/dsp/fourier"
+	"gonum.org/v1/gonum/stat"
+)
+
+// SaliencyMap - Per pixel saliency in the range [0, 1].
+// Indexed [x][y] like ImageColors.
+type SaliencyMap [][]float64
+
+// Saliency Defaults
+const (
+	spectralResidualSize  = 64
+	spectralResidualSigma = 2.5
+)
+
+// newSaliencyMap - Create a SaliencyMap of width x height
+func newSaliencyMap(width, height int) SaliencyMap {
+	sm := make(SaliencyMap, width)
+	for x := range sm {
+		sm[x] = make([]float64, height)
+	}
+	return sm
+}
+
+// size - Width and Height of the SaliencyMap
+func (sm SaliencyMap) size() (int, int) {
+	if len(sm) == 0 {
+		return 0, 0
+	}
+	return len(sm), len(sm[0])
+}
+
+// Resize - Resample the SaliencyMap to width x height (bilinear)
+func (sm SaliencyMap) Resize(width, height int) SaliencyMap {
+	w, h := sm.size()
+	if w == width && h == height {
+		return sm
+	}
+	res := newSaliencyMap(width, height)
+	if w == 0 || h == 0 {
+		return res
+	}
+	for x := 0; x < width; x++ {
+		fx := math.Max(0, (float64(x)+0.5)*float64(w)/float64(width)-0.5)
+		x0 := minInt(int(fx), w-1)
+		x1 := minInt(x0+1, w-1)
+		tx := fx - float64(x0)
+		for y := 0; y < height; y++ {
+			fy := math.Max(0, (float64(y)+0.5)*float64(h)/float64(height)-0.5)
+			y0 := minInt(int(fy), h-1)
+			y1 := minInt(y0+1, h-1)
+			ty := fy - float64(y0)
+			res[x][y] = (sm[x0][y0]*(1-tx)+sm[x1][y0]*tx)*(1-ty) + (sm[x0][y1]*(1-tx)+sm[x1][y1]*tx)*ty
+		}
+	}
+	return res
+}
+
+// normalize - Scale the SaliencyMap to the range [0, 1]
+func (sm SaliencyMap) normalize() SaliencyMap {
+	lo, hi := math.MaxFloat64, -math.MaxFloat64
+	for x := range sm {
+		for _, v := range sm[x] {
+			lo, hi = math.Min(lo, v), math.Max(hi, v)
+		}
+	}
+	for x := range sm {
+		for y, v := range sm[x] {
+			if hi > lo {
+				sm[x][y] = (v - lo) / (hi - lo)
+			} else {
+				sm[x][y] = 1
+			}
+		}
+	}
+	return sm
+}
+
+// EdgeDensitySaliency - Saliency from the local density of edges.
+// Edges are detected with Box.ImageEdges and averaged over a window of
+// 1/8th of the smallest image dimension.
+func EdgeDensitySaliency(m image.Image, radius float64) SaliencyMap {
+	bounds := m.Bounds()
+	box := NewBox(bounds.Min.X, bounds.Min.Y, bounds.Max.X, bounds.Max.Y)
+	edges := box.ImageEdges(m, radius)
+	eb := edges.Bounds()
+	width, height := eb.Dx(), eb.Dy()
+	sm := newSaliencyMap(width, height)
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			_, _, l := newColorful(edges.At(x+eb.Min.X, y+eb.Min.Y).RGBA()).Hsl()
+			sm[x][y] = l
+		}
+	}
+	window := maxInt(minInt(width, height)/8, 1)
+	return sm.boxBlur(window).normalize()
+}
+
+// boxBlur - Mean over a (2r+1)^2 window using a summed area table
+func (sm SaliencyMap) boxBlur(r int) SaliencyMap {
+	w, h := sm.size()
+	sat := make([]float64, (w+1)*(h+1))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			sat[(y+1)*(w+1)+x+1] = sm[x][y] + sat[y*(w+1)+x+1] + sat[(y+1)*(w+1)+x] - sat[y*(w+1)+x]
+		}
+	}
+	res := newSaliencyMap(w, h)
+	for x := 0; x < w; x++ {
+		x0, x1 := maxInt(x-r, 0), minInt(x+r+1, w)
+		for y := 0; y < h; y++ {
+			y0, y1 := maxInt(y-r, 0), minInt(y+r+1, h)
+			sum := sat[y1*(w+1)+x1] - sat[y0*(w+1)+x1] - sat[y1*(w+1)+x0] + sat[y0*(w+1)+x0]
+			res[x][y] = sum / float64((x1-x0)*(y1-y0))
+		}
+	}
+	return res
+}
+
+// SpectralResidualSaliency - Saliency using the spectral residual approach
+// (Hou and Zhang 2007), computed at 64x64 and resampled to the image size.
+func SpectralResidualSaliency(m image.Image) SaliencyMap {
+	bounds := m.Bounds()
+	if bounds.Empty() {
+		return nil
+	}
+	const n = spectralResidualSize
+	small := Downscale(m, n, n, GammaAveraging)
+
+	// Rows of the 2D spectrum, indexed [y][x]
+	spectrum := make([][]complex128, n)
+	for y := range spectrum {
+		spectrum[y] = make([]complex128, n)
+		for x := range spectrum[y] {
+			_, _, l := newColorful(small.At(x, y).RGBA()).Hsl()
+			spectrum[y][x] = complex(l, 0)
+		}
+	}
+	fft := fourier.NewCmplxFFT(n)
+	fft2D(fft, spectrum, false)
+
+	// Log amplitude and phase
+	logAmp := newSaliencyMap(n, n)
+	phase := newSaliencyMap(n, n)
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			c := spectrum[y][x]
+			logAmp[x][y] = math.Log(cmplx.Abs(c) + 1e-9)
+			phase[x][y] = cmplx.Phase(c)
+		}
+	}
+	avg := logAmp.boxBlur(1)
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			residual := logAmp[x][y] - avg[x][y]
+			spectrum[y][x] = cmplx.Exp(complex(residual, phase[x][y]))
+		}
+	}
+	fft2D(fft, spectrum, true)
+
+	sm := newSaliencyMap(n, n)
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			a := cmplx.Abs(spectrum[y][x])
+			sm[x][y] = a * a
+		}
+	}
+	sm = sm.gaussianBlur(spectralResidualSigma).normalize()
+	return sm.Resize(bounds.Dx(), bounds.Dy())
+}
+
+// fft2D - In place 2D FFT of rows [y][x] using the separable property
+func fft2D(fft *fourier.CmplxFFT, data [][]complex128, inverse bool) {
+	n := len(data)
+	transform := fft.Coefficients
+	if inverse {
+		transform = fft.Sequence
+	}
+	for y := range data {
+		transform(data[y], data[y])
+	}
+	col := make([]complex128, n)
+	for x := 0; x < n; x++ {
+		for y := 0; y < n; y++ {
+			col[y] = data[y][x]
+		}
+		transform(col, col)
+		for y := 0; y < n; y++ {
+			data[y][x] = col[y]
+		}
+	}
+}
+
+// gaussianBlur - Separable gaussian blur
+func (sm SaliencyMap) gaussianBlur(sigma float64) SaliencyMap {
+	r := int(math.Ceil(sigma * 3))
+	kernel := make([]float64, 2*r+1)
+	var sum float64
+	for i := range kernel {
+		d := float64(i - r)
+		kernel[i] = math.Exp(-d * d / (2 * sigma * sigma))
+		sum += kernel[i]
+	}
+	for i := range kernel {
+		kernel[i] /= sum
+	}
+	w, h := sm.size()
+	tmp := newSaliencyMap(w, h)
+	res := newSaliencyMap(w, h)
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			for i, k := range kernel {
+				xi := minInt(maxInt(x+i-r, 0), w-1)
+				tmp[x][y] += sm[xi][y] * k
+			}
+		}
+	}
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			for i, k := range kernel {
+				yi := minInt(maxInt(y+i-r, 0), h-1)
+				res[x][y] += tmp[x][yi] * k
+			}
+		}
+	}
+	return res
+}
+
+// ProminentColorsWeighted - Return Prominent Colors with every pixel weighted by its saliency.
+// The SaliencyMap is resampled to the size of ImageColors when needed.
+// (limit) percentage limit of promiment colors to return
+func (ic *ImageColors) ProminentColorsWeighted(limit float64, sm SaliencyMap) ProminentColors {
+	width := len(*ic)
+	height := 0
+	if width > 0 {
+		height = len((*ic)[0])
+	}
+	sm = sm.Resize(width, height)
+
+	var pc ProminentColors
+	var total float64
+	result := make(map[MaterialColor]float64)
+	weights := make([]float64, 0, width*height)
+	for x, col := range *ic {
+		for y, c := range col {
+			w := sm[x][y]
+			result[closestMaterialColor(c)] += w
+			weights = append(weights, w)
+			total += w
+		}
+	}
+	if total == 0 {
+		return ic.ProminentColors(limit)
+	}
+	for name, w := range result {
+		if w/total > limit {
+			pc.Colors = append(pc.Colors, ProminentColor{Color: name, W: w / total})
+		}
+	}
+	sort.Sort(pc)
+
+	// Hue is left unset like in ProminentColors, a linear mean of a circular hue is meaningless
+	a, b := stat.MeanStdDev(ic.saturations(), weights)
+	pc.Saturation = [2]float64{a, b}
+	light := ic.lightness()
+	a, b = stat.MeanStdDev(light, weights)
+	pc.Lightness = [2]float64{a, b}
+	pc.Colorfulness = math.Sqrt(pc.Saturation[0] + pc.Saturation[1])
+
+	sort.Sort(weightedValues{light, weights})
+	pc.Qlightness = stat.Quantile(0.70, stat.Empirical, light, weights)
+	return pc
+}
+
+// weightedValues - Sorts values and their weights together
+type weightedValues struct {
+	values, weights []float64
+}
+
+func (wv weightedValues) Len() int           { return len(wv.values) }
+func (wv weightedValues) Less(i, j int) bool { return wv.values[i] < wv.values[j] }
+func (wv weightedValues) Swap(i, j int) {
+	wv.values[i], wv.values[j] = wv.values[j], wv.values[i]
+	wv.weights[i], wv.weights[j] = wv.weights[j], wv.weights[i]
+}
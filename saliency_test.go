@@ -0,0 +1,113 @@
+package imagecolor
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// testSaliency - SaliencyMap of width x height with the value v(x, y)
+func testSaliency(width, height int, v func(x, y int) float64) SaliencyMap {
+	sm := newSaliencyMap(width, height)
+	for x := range sm {
+		for y := range sm[x] {
+			sm[x][y] = v(x, y)
+		}
+	}
+	return sm
+}
+
+func TestSaliencyMapResize(t *testing.T) {
+	sm := testSaliency(2, 1, func(x, y int) float64 { return float64(x) })
+	want := []float64{0, 0.25, 0.75, 1}
+	res := sm.Resize(4, 1)
+	for x, w := range want {
+		if math.Abs(res[x][0]-w) > 1e-9 {
+			t.Errorf("Resize at %d was incorrect, got: %v, want: %v.", x, res[x][0], w)
+		}
+	}
+	constant := testSaliency(3, 5, func(x, y int) float64 { return 0.4 }).Resize(7, 2)
+	for x := range constant {
+		for y, v := range constant[x] {
+			if math.Abs(v-0.4) > 1e-9 {
+				t.Fatalf("Resize of a constant at %v,%v was incorrect, got: %v, want: %v.", x, y, v, 0.4)
+			}
+		}
+	}
+	if w, h := (SaliencyMap{}).Resize(3, 2).size(); w != 3 || h != 2 {
+		t.Errorf("Resize of an empty map was incorrect, got: %vx%v, want: %vx%v.", w, h, 3, 2)
+	}
+}
+
+func TestProminentColorsWeighted(t *testing.T) {
+	ic := testHalves(20, 10)
+	tests := []struct {
+		name      string
+		sm        SaliencyMap
+		red, blue float64
+	}{
+		{"uniform", testSaliency(20, 10, func(x, y int) float64 { return 1 }), 0.5, 0.5},
+		{"red salient", testSaliency(20, 10, func(x, y int) float64 { return 1 + 2*float64(1-x/10) }), 0.75, 0.25},
+		{"resized", testSaliency(2, 1, func(x, y int) float64 { return float64(x) }), 0.125, 0.875}, // bilinear
+		{"zero", newSaliencyMap(20, 10), 0.5, 0.5},
+	}
+	for _, tt := range tests {
+		pc := ic.ProminentColorsWeighted(0, tt.sm)
+		var red, blue float64
+		for _, c := range pc.Colors {
+			switch c.Color {
+			case materialRed:
+				red = c.W
+			case materialBlue:
+				blue = c.W
+			}
+		}
+		if math.Abs(red-tt.red) > 1e-9 || math.Abs(blue-tt.blue) > 1e-9 {
+			t.Errorf("%s weights was incorrect, got: red %v blue %v, want: red %v blue %v.", tt.name, red, blue, tt.red, tt.blue)
+		}
+	}
+
+	// A uniform map gives the statistics of ProminentColors
+	got, want := ic.ProminentColorsWeighted(0, tests[0].sm), ic.ProminentColors(0)
+	if math.Abs(got.Lightness[0]-want.Lightness[0]) > 1e-9 || math.Abs(got.Saturation[0]-want.Saturation[0]) > 1e-9 {
+		t.Errorf("Uniform statistics was incorrect, got: %v %v, want: %v %v.", got.Lightness, got.Saturation, want.Lightness, want.Saturation)
+	}
+	if got.Hue != want.Hue {
+		t.Errorf("Hue was incorrect, got: %v, want: %v.", got.Hue, want.Hue)
+	}
+}
+
+func TestSpectralResidualSaliency(t *testing.T) {
+	// A regular lattice of dots is expected, a dark blob is not
+	m := testFill(128, 128, color.RGBA{0x80, 0x80, 0x80, 0xff})
+	for y := 0; y < 128; y++ {
+		for x := 0; x < 128; x++ {
+			if x%8 < 2 && y%8 < 2 {
+				m.SetRGBA(x, y, color.RGBA{0xff, 0xff, 0xff, 0xff})
+			}
+			if x >= 88 && x < 100 && y >= 32 && y < 44 {
+				m.SetRGBA(x, y, color.RGBA{0, 0, 0, 0xff})
+			}
+		}
+	}
+	sm := SpectralResidualSaliency(m)
+	if w, h := sm.size(); w != 128 || h != 128 {
+		t.Fatalf("Size was incorrect, got: %vx%v, want: %vx%v.", w, h, 128, 128)
+	}
+	var mean float64
+	for x := range sm {
+		for y, v := range sm[x] {
+			if v < 0 || v > 1 {
+				t.Fatalf("Saliency at %v,%v was incorrect, got: %v, want: in [0, 1].", x, y, v)
+			}
+			mean += v / (128 * 128)
+		}
+	}
+	if blob := sm[94][38]; blob < 0.5 || blob < 3*mean {
+		t.Errorf("Saliency was incorrect, got: blob %v mean %v, want: blob salient.", blob, mean)
+	}
+	if sm := SpectralResidualSaliency(image.NewRGBA(image.Rectangle{})); sm != nil {
+		t.Errorf("Empty saliency was incorrect, got: %v, want: nil.", sm)
+	}
+}
@@ -0,0 +1,422 @@
+package imagecolor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Errors
+const (
+	ErrorFocusMeasure    = "Unknown focus measure"
+	ErrorBlurCalibration = "Blur calibration needs sharp and blurry images"
+	ErrorBlurSize        = "Image is too small to measure sharpness"
+)
+
+// FocusMeasure - Measure of the sharpness of an image, computed on its 8 bit luma.
+// Larger values are sharper.
+type FocusMeasure uint8
+
+// Focus Measures
+const (
+	// VarianceOfLaplacian is the variance of the 3x3 Laplacian (Pech-Pacheco et al. 2000).
+	VarianceOfLaplacian FocusMeasure = iota
+	// Tenengrad is the mean squared Sobel gradient magnitude (Krotkov 1987).
+	Tenengrad
+	// ModifiedLaplacian is the mean of |d2I/dx2| + |d2I/dy2| (Nayar and Nakagawa 1994).
+	ModifiedLaplacian
+)
+
+// Blur Defaults
+const (
+	defaultBlurSize = 500 // largest side images are measured at, same scale as CalcCompositionBoxes
+	blurMapWindow   = 32  // the blur map averages over 1/blurMapWindow of the smallest dimension
+	minBlurSize     = 3   // focus responses need a pixel that is not on the border
+)
+
+// focusMeasureNames - Names of the FocusMeasures, as parsed by ParseFocusMeasure
+var focusMeasureNames = [...]string{"laplacian", "tenengrad", "modified-laplacian"}
+
+// defaultBlurThresholds - Thresholds of the FocusMeasures at defaultBlurSize, see DetectBlur
+var defaultBlurThresholds = [...]float64{100, 9000, 8.5}
+
+// String - Name of the measure
+func (fm FocusMeasure) String() string {
+	if int(fm) < len(focusMeasureNames) {
+		return focusMeasureNames[fm]
+	}
+	return fmt.Sprintf("FocusMeasure(%d)", fm)
+}
+
+// ParseFocusMeasure - FocusMeasure from its name: laplacian, tenengrad or modified-laplacian
+func ParseFocusMeasure(s string) (FocusMeasure, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for i, name := range focusMeasureNames {
+		if s == name {
+			return FocusMeasure(i), nil
+		}
+	}
+	return 0, errors.New(ErrorFocusMeasure)
+}
+
+// MarshalText - Encode the measure as its name
+func (fm FocusMeasure) MarshalText() ([]byte, error) {
+	if int(fm) >= len(focusMeasureNames) {
+		return nil, errors.New(ErrorFocusMeasure)
+	}
+	return []byte(fm.String()), nil
+}
+
+// UnmarshalText - Decode a measure from its name
+func (fm *FocusMeasure) UnmarshalText(text []byte) error {
+	m, err := ParseFocusMeasure(string(text))
+	if err != nil {
+		return err
+	}
+	*fm = m
+	return nil
+}
+
+// DefaultThreshold - Blur threshold of the measure for images measured at 500 pixels,
+// as used by DetectBlur when BlurOptions.Threshold is 0
+func (fm FocusMeasure) DefaultThreshold() float64 {
+	if int(fm) < len(defaultBlurThresholds) {
+		return defaultBlurThresholds[fm]
+	}
+	return 0
+}
+
+// Sharpness - Focus measures of an image or of a region of an image
+type Sharpness struct {
+	Rect                image.Rectangle
+	VarianceOfLaplacian float64
+	Tenengrad           float64
+	ModifiedLaplacian   float64
+}
+
+// Measure - Value of a FocusMeasure
+func (s Sharpness) Measure(fm FocusMeasure) float64 {
+	switch fm {
+	case Tenengrad:
+		return s.Tenengrad
+	case ModifiedLaplacian:
+		return s.ModifiedLaplacian
+	}
+	return s.VarianceOfLaplacian
+}
+
+// sharpnessJSON - JSON schema of Sharpness
+type sharpnessJSON struct {
+	Rect                [4]int  `json:"rect"` // x1, y1, x2, y2
+	VarianceOfLaplacian float64 `json:"laplacian"`
+	Tenengrad           float64 `json:"tenengrad"`
+	ModifiedLaplacian   float64 `json:"modified_laplacian"`
+}
+
+// MarshalJSON - Encode Sharpness
+func (s Sharpness) MarshalJSON() ([]byte, error) {
+	return json.Marshal(sharpnessJSON{
+		Rect:                [4]int{s.Rect.Min.X, s.Rect.Min.Y, s.Rect.Max.X, s.Rect.Max.Y},
+		VarianceOfLaplacian: finite(s.VarianceOfLaplacian),
+		Tenengrad:           finite(s.Tenengrad),
+		ModifiedLaplacian:   finite(s.ModifiedLaplacian),
+	})
+}
+
+// UnmarshalJSON - Decode Sharpness
+func (s *Sharpness) UnmarshalJSON(data []byte) error {
+	var sj sharpnessJSON
+	if err := json.Unmarshal(data, &sj); err != nil {
+		return err
+	}
+	*s = Sharpness{
+		Rect:                image.Rect(sj.Rect[0], sj.Rect[1], sj.Rect[2], sj.Rect[3]),
+		VarianceOfLaplacian: sj.VarianceOfLaplacian,
+		Tenengrad:           sj.Tenengrad,
+		ModifiedLaplacian:   sj.ModifiedLaplacian,
+	}
+	return nil
+}
+
+// MeasureSharpness - Focus measures of the whole image, at its full resolution.
+// Images smaller than 3x3 pixels measure 0.
+func MeasureSharpness(m image.Image) Sharpness {
+	return newFocusPlanes(m).measure(m.Bounds())
+}
+
+// MeasureSharpnessGrid - Focus measures of the boxes of a composition grid, at the full
+// resolution of the image
+func MeasureSharpnessGrid(m image.Image, grid CompositionGrid) ([]Sharpness, error) {
+	if !blurSizeValid(m) {
+		return nil, errors.New(ErrorBlurSize)
+	}
+	boxes := grid.Boxes(m.Bounds())
+	if len(boxes) == 0 {
+		return nil, errors.New(ErrorCompositionGrid)
+	}
+	fp := newFocusPlanes(m)
+	res := make([]Sharpness, len(boxes))
+	for i, b := range boxes {
+		res[i] = fp.measure(b.Rect)
+	}
+	return res, nil
+}
+
+// BlurOptions - Options of DetectBlur, NewBlurMap and CalibrateBlur.
+// Zero values are replaced with defaults.
+type BlurOptions struct {
+	Measure   FocusMeasure
+	Threshold float64          // images whose sharpest region measures below Threshold are blurry, Measure.DefaultThreshold() by default
+	Size      int              // largest side images are downscaled to before measuring, 500 by default, negative keeps the image size
+	Grid      *CompositionGrid // regions of DetectBlur, a 3x3 grid when nil
+}
+
+func (opts BlurOptions) withDefaults() BlurOptions {
+	if opts.Threshold <= 0 {
+		opts.Threshold = opts.Measure.DefaultThreshold()
+	}
+	if opts.Size == 0 {
+		opts.Size = defaultBlurSize
+	}
+	if opts.Grid == nil {
+		grid := NewNxMGrid(3, 3)
+		opts.Grid = &grid
+	}
+	return opts
+}
+
+// BlurReport - Result of DetectBlur. Rectangles are in the coordinates of the measured
+// (downscaled) image.
+type BlurReport struct {
+	Measure   FocusMeasure `json:"measure"`
+	Threshold float64      `json:"threshold"`
+	Score     float64      `json:"score"` // Measure of the sharpest region
+	Blurry    bool         `json:"blurry"`
+	Image     Sharpness    `json:"image"`
+	Regions   []Sharpness  `json:"regions"`
+}
+
+// DetectBlur - Classify an image as blurry when the sharpest region of opts.Grid measures
+// below opts.Threshold. Using the sharpest region keeps photos with a sharp subject and a
+// blurred background (shallow depth of field) sharp.
+//
+// Measures depend on the scale of the image, so images are downscaled to opts.Size first.
+// The default thresholds were calibrated on sharp photos blurred with a gaussian at 500
+// pixels: every photo scored above them at a sigma of 0.5 pixel and below them at a sigma
+// of 1 pixel. Graphics and very high contrast images score higher. Use CalibrateBlur to
+// choose a threshold from labeled examples of your own images.
+func DetectBlur(m image.Image, opts BlurOptions) (BlurReport, error) {
+	opts = opts.withDefaults()
+	if int(opts.Measure) >= len(focusMeasureNames) {
+		return BlurReport{}, errors.New(ErrorFocusMeasure)
+	}
+	m = blurDownscale(m, opts.Size)
+	if !blurSizeValid(m) {
+		return BlurReport{}, errors.New(ErrorBlurSize)
+	}
+	boxes := opts.Grid.Boxes(m.Bounds())
+	if len(boxes) == 0 {
+		return BlurReport{}, errors.New(ErrorCompositionGrid)
+	}
+	fp := newFocusPlanes(m)
+	report := BlurReport{Measure: opts.Measure, Threshold: opts.Threshold, Image: fp.measure(m.Bounds())}
+	for i, b := range boxes {
+		report.Regions = append(report.Regions, fp.measure(b.Rect))
+		if v := report.Regions[i].Measure(opts.Measure); i == 0 || v > report.Score {
+			report.Score = v
+		}
+	}
+	report.Blurry = report.Score < opts.Threshold
+	return report, nil
+}
+
+// CalibrateBlur - Threshold of opts.Measure that best separates sharp from blurry
+// examples with DetectBlur. The threshold is the geometric mean of the two scores around
+// the split with the fewest misclassified examples.
+// The Threshold of opts is ignored.
+func CalibrateBlur(sharp, blurry []image.Image, opts BlurOptions) (float64, error) {
+	if len(sharp) == 0 || len(blurry) == 0 {
+		return 0, errors.New(ErrorBlurCalibration)
+	}
+	type example struct {
+		score float64
+		sharp bool
+	}
+	var examples []example
+	for i, images := range [][]image.Image{blurry, sharp} {
+		for _, m := range images {
+			report, err := DetectBlur(m, opts)
+			if err != nil {
+				return 0, err
+			}
+			examples = append(examples, example{report.Score, i == 1})
+		}
+	}
+	sort.SliceStable(examples, func(i, j int) bool { return examples[i].score < examples[j].score })
+
+	// Errors of a threshold between examples[i-1] and examples[i]: sharp examples below
+	// and blurry examples above
+	errs := len(blurry)
+	best, bestErrs := 0, errs
+	for i := 1; i <= len(examples); i++ {
+		if examples[i-1].sharp {
+			errs++
+		} else {
+			errs--
+		}
+		if i < len(examples) && examples[i].score == examples[i-1].score {
+			continue
+		}
+		if errs < bestErrs {
+			best, bestErrs = i, errs
+		}
+	}
+	switch best {
+	case 0:
+		return examples[0].score, nil
+	case len(examples):
+		return math.Nextafter(examples[best-1].score, math.Inf(1)), nil
+	}
+	if lo, hi := examples[best-1].score, examples[best].score; lo > 0 {
+		return math.Sqrt(lo * hi), nil
+	}
+	return examples[best].score / 2, nil
+}
+
+// BlurMap - Per pixel blur in the range [0, 1], 0.5 at the blur threshold and 1 without
+// detail. Areas without texture (sky, walls) read as blurry.
+// Indexed [x][y] like ImageColors.
+type BlurMap [][]float64
+
+// NewBlurMap - Blur of every pixel of the image, from the focus measure over a window of
+// 1/32 of the smallest dimension of the downscaled image, resampled to the image size
+func NewBlurMap(m image.Image, opts BlurOptions) (BlurMap, error) {
+	opts = opts.withDefaults()
+	if int(opts.Measure) >= len(focusMeasureNames) {
+		return nil, errors.New(ErrorFocusMeasure)
+	}
+	bounds := m.Bounds()
+	scaled := blurDownscale(m, opts.Size)
+	if !blurSizeValid(scaled) {
+		return nil, errors.New(ErrorBlurSize)
+	}
+	fp := newFocusPlanes(scaled)
+	w, h := fp.width, fp.height
+	r := maxInt(minInt(w, h)/blurMapWindow, 1)
+	bm := newSaliencyMap(w, h)
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			s := fp.measure(image.Rect(x-r, y-r, x+r+1, y+r+1).Add(fp.min))
+			bm[x][y] = opts.Threshold / (opts.Threshold + s.Measure(opts.Measure))
+		}
+	}
+	return BlurMap(bm.Resize(bounds.Dx(), bounds.Dy())), nil
+}
+
+// blurDownscale - Downscale m so that its largest side is at most size (negative keeps the size)
+func blurDownscale(m image.Image, size int) image.Image {
+	b := m.Bounds()
+	largest := maxInt(b.Dx(), b.Dy())
+	if size < 0 || largest <= size {
+		return m
+	}
+	scale := float64(size) / float64(largest)
+	return Downscale(m, maxInt(int(float64(b.Dx())*scale), 1), maxInt(int(float64(b.Dy())*scale), 1), GammaAveraging)
+}
+
+// blurSizeValid - Whether m is large enough to have focus responses
+func blurSizeValid(m image.Image) bool {
+	b := m.Bounds()
+	return b.Dx() >= minBlurSize && b.Dy() >= minBlurSize
+}
+
+// focusPlanes - Summed area tables of the per pixel focus responses of an image.
+// Responses are defined for pixels that are not on the border of the image.
+type focusPlanes struct {
+	min           image.Point
+	width, height int
+	lap, lap2     []float64 // Laplacian and squared Laplacian
+	ten           []float64 // squared Sobel gradient magnitude
+	ml            []float64 // modified Laplacian
+}
+
+// newFocusPlanes - Focus responses of the luma of m
+func newFocusPlanes(m image.Image) *focusPlanes {
+	b := m.Bounds()
+	w, h := b.Dx(), b.Dy()
+	fp := &focusPlanes{min: b.Min, width: w, height: h}
+	n := (w + 1) * (h + 1)
+	fp.lap, fp.lap2, fp.ten, fp.ml = make([]float64, n), make([]float64, n), make([]float64, n), make([]float64, n)
+	luma := lumaPlane(m)
+	for y := 0; y < h; y++ {
+		var lap, lap2, ten, ml float64 // sums of the row
+		for x := 0; x < w; x++ {
+			if x > 0 && y > 0 && x < w-1 && y < h-1 {
+				i := y*w + x
+				c := luma[i]
+				l, r, t, d := luma[i-1], luma[i+1], luma[i-w], luma[i+w]
+				tl, tr, dl, dr := luma[i-w-1], luma[i-w+1], luma[i+w-1], luma[i+w+1]
+				dxx, dyy := l+r-2*c, t+d-2*c
+				gx := (tr + 2*r + dr) - (tl + 2*l + dl)
+				gy := (dl + 2*d + dr) - (tl + 2*t + tr)
+				lap += dxx + dyy
+				lap2 += (dxx + dyy) * (dxx + dyy)
+				ten += gx*gx + gy*gy
+				ml += math.Abs(dxx) + math.Abs(dyy)
+			}
+			j := (y+1)*(w+1) + x + 1
+			fp.lap[j] = fp.lap[j-w-1] + lap
+			fp.lap2[j] = fp.lap2[j-w-1] + lap2
+			fp.ten[j] = fp.ten[j-w-1] + ten
+			fp.ml[j] = fp.ml[j-w-1] + ml
+		}
+	}
+	return fp
+}
+
+// measure - Focus measures over the pixels of r that have a response
+func (fp *focusPlanes) measure(r image.Rectangle) Sharpness {
+	s := Sharpness{Rect: r}
+	in := r.Sub(fp.min).Intersect(image.Rect(1, 1, fp.width-1, fp.height-1))
+	if in.Empty() {
+		return s
+	}
+	n := float64(in.Dx() * in.Dy())
+	sum := func(sat []float64) float64 {
+		w := fp.width + 1
+		return sat[in.Max.Y*w+in.Max.X] - sat[in.Min.Y*w+in.Max.X] - sat[in.Max.Y*w+in.Min.X] + sat[in.Min.Y*w+in.Min.X]
+	}
+	mean := sum(fp.lap) / n
+	s.VarianceOfLaplacian = math.Max(sum(fp.lap2)/n-mean*mean, 0)
+	s.Tenengrad = sum(fp.ten) / n
+	s.ModifiedLaplacian = sum(fp.ml) / n
+	return s
+}
+
+// lumaPlane - Gamma encoded Rec. 709 luma of m in the range [0, 255], row major
+func lumaPlane(m image.Image) []float64 {
+	b := m.Bounds()
+	w, h := b.Dx(), b.Dy()
+	luma := make([]float64, w*h)
+	// The Y of YCbCr images is BT.601 luma, so they are converted to RGB like other images
+	switch img := m.(type) {
+	case *image.Gray:
+		for y := 0; y < h; y++ {
+			for x, v := range img.Pix[img.PixOffset(b.Min.X, y+b.Min.Y):][:w] {
+				luma[y*w+x] = float64(v)
+			}
+		}
+	default:
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				r, g, bl, _ := m.At(x+b.Min.X, y+b.Min.Y).RGBA()
+				luma[y*w+x] = (lumaR*float64(r) + lumaG*float64(g) + lumaB*float64(bl)) / 257
+			}
+		}
+	}
+	return luma
+}
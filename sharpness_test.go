@@ -0,0 +1,317 @@
+package imagecolor
+
+import (
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"math"
+	"os"
+	"reflect"
+	"testing"
+)
+
+// testTexture - Gray image with pseudo random pixels of full contrast in rect and a flat
+// gray elsewhere
+func testTexture(bounds, rect image.Rectangle) *image.Gray {
+	return testTextureContrast(bounds, rect, 127)
+}
+
+// testTextureContrast - Gray image with pseudo random pixels in the range
+// [128-contrast, 128+contrast] in rect and a flat gray elsewhere
+func testTextureContrast(bounds, rect image.Rectangle, contrast int) *image.Gray {
+	m := image.NewGray(bounds)
+	seed := uint32(1)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			seed = seed*1664525 + 1013904223
+			v := uint8(128)
+			if image.Pt(x, y).In(rect) {
+				v = uint8(128 - contrast + int(seed>>24)*2*contrast/255)
+			}
+			m.Pix[m.PixOffset(x, y)] = v
+		}
+	}
+	return m
+}
+
+// testGaussianBlur - m blurred with a gaussian of sigma pixels, edges extended
+func testGaussianBlur(m image.Image, sigma float64) *image.RGBA {
+	b := m.Bounds()
+	r := int(math.Ceil(3 * sigma))
+	kernel := make([]float64, 2*r+1)
+	var sum float64
+	for i := range kernel {
+		d := float64(i - r)
+		kernel[i] = math.Exp(-d * d / (2 * sigma * sigma))
+		sum += kernel[i]
+	}
+	w, h := b.Dx(), b.Dy()
+	src := make([][3]float64, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.RGBAModel.Convert(m.At(x+b.Min.X, y+b.Min.Y)).(color.RGBA)
+			src[y*w+x] = [3]float64{float64(c.R), float64(c.G), float64(c.B)}
+		}
+	}
+	pass := func(in [][3]float64, dx, dy int) [][3]float64 {
+		out := make([][3]float64, len(in))
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				var acc [3]float64
+				for i, k := range kernel {
+					sx := minInt(maxInt(x+(i-r)*dx, 0), w-1)
+					sy := minInt(maxInt(y+(i-r)*dy, 0), h-1)
+					for c := range acc {
+						acc[c] += in[sy*w+sx][c] * k / sum
+					}
+				}
+				out[y*w+x] = acc
+			}
+		}
+		return out
+	}
+	blurred := pass(pass(src, 1, 0), 0, 1)
+	res := image.NewRGBA(image.Rect(0, 0, w, h))
+	for i, c := range blurred {
+		res.Pix[i*4], res.Pix[i*4+1], res.Pix[i*4+2], res.Pix[i*4+3] = uint8(math.Round(c[0])), uint8(math.Round(c[1])), uint8(math.Round(c[2])), 0xff
+	}
+	return res
+}
+
+// testBoxBlur - m blurred n times with a 3x3 box filter
+func testBoxBlur(m *image.Gray, n int) *image.Gray {
+	b := m.Bounds()
+	for ; n > 0; n-- {
+		res := image.NewGray(b)
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				sum, count := 0, 0
+				for dy := -1; dy <= 1; dy++ {
+					for dx := -1; dx <= 1; dx++ {
+						if p := image.Pt(x+dx, y+dy); p.In(b) {
+							sum += int(m.GrayAt(p.X, p.Y).Y)
+							count++
+						}
+					}
+				}
+				res.Pix[res.PixOffset(x, y)] = uint8((sum + count/2) / count)
+			}
+		}
+		m = res
+	}
+	return m
+}
+
+func TestMeasureSharpness(t *testing.T) {
+	rect := image.Rect(0, 0, 120, 90)
+	if s := MeasureSharpness(testTexture(rect, image.Rectangle{})); s.VarianceOfLaplacian != 0 || s.Tenengrad != 0 || s.ModifiedLaplacian != 0 {
+		t.Errorf("Flat sharpness was incorrect, got: %+v, want: %v.", s, 0)
+	}
+
+	sharp := testTexture(rect, rect)
+	prev := MeasureSharpness(sharp)
+	for n := 1; n <= 3; n++ {
+		s := MeasureSharpness(testBoxBlur(sharp, n))
+		for _, fm := range []FocusMeasure{VarianceOfLaplacian, Tenengrad, ModifiedLaplacian} {
+			if s.Measure(fm) >= prev.Measure(fm) {
+				t.Errorf("%v of %d blurs was incorrect, got: %v, want: less than %v.", fm, n, s.Measure(fm), prev.Measure(fm))
+			}
+		}
+		prev = s
+	}
+
+	// Bounds that do not start at the origin and other image types give the same measures
+	offset := testTexture(rect.Add(image.Pt(7, 3)), rect.Add(image.Pt(7, 3)))
+	want := MeasureSharpness(sharp)
+	want.Rect = offset.Bounds()
+	rgba := image.NewRGBA(offset.Bounds())
+	for y := offset.Rect.Min.Y; y < offset.Rect.Max.Y; y++ {
+		for x := offset.Rect.Min.X; x < offset.Rect.Max.X; x++ {
+			rgba.Set(x, y, offset.At(x, y))
+		}
+	}
+	for _, m := range []image.Image{offset, rgba} {
+		if s := MeasureSharpness(m); !reflect.DeepEqual(s, want) {
+			t.Errorf("%T sharpness was incorrect, got: %+v, want: %+v.", m, s, want)
+		}
+	}
+
+	regions, err := MeasureSharpnessGrid(testTexture(rect, image.Rect(0, 0, 40, 45)), NewNxMGrid(3, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(regions) != 6 || regions[0].Rect != image.Rect(0, 0, 40, 45) || regions[0].VarianceOfLaplacian == 0 || regions[5].VarianceOfLaplacian != 0 {
+		t.Errorf("Regions was incorrect, got: %+v, want: %v.", regions, "6 regions, the first sharp")
+	}
+}
+
+func TestDetectBlur(t *testing.T) {
+	rect := image.Rect(0, 0, 300, 200)
+	sharp := testTexture(rect, rect)
+	blurry := testBoxBlur(sharp, 3)
+	tests := []struct {
+		name   string
+		m      image.Image
+		blurry bool
+	}{
+		{"sharp", sharp, false},
+		{"blurry", blurry, true},
+	}
+	for _, tt := range tests {
+		for _, fm := range []FocusMeasure{VarianceOfLaplacian, Tenengrad, ModifiedLaplacian} {
+			report, err := DetectBlur(tt.m, BlurOptions{Measure: fm})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if report.Blurry != tt.blurry || len(report.Regions) != 9 || report.Threshold != fm.DefaultThreshold() {
+				t.Errorf("%s %v was incorrect, got: blurry %v score %v, want: blurry %v.", tt.name, fm, report.Blurry, report.Score, tt.blurry)
+			}
+		}
+	}
+
+	// A sharp subject on a flat background is sharp, although the whole image measures
+	// less than the threshold
+	subject := testTextureContrast(rect, image.Rect(110, 80, 150, 120), 16)
+	if report, _ := DetectBlur(subject, BlurOptions{}); report.Blurry || report.Image.VarianceOfLaplacian >= report.Threshold {
+		t.Errorf("Subject was incorrect, got: blurry %v image %v, want: sharp with image less than %v.", report.Blurry, report.Image.VarianceOfLaplacian, report.Threshold)
+	}
+
+	report, err := DetectBlur(sharp, BlurOptions{Measure: Tenengrad})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(report)
+	if err != nil {
+		t.Fatalf("Error encoding JSON: %v", err)
+	}
+	var decoded BlurReport
+	if err = json.Unmarshal(b, &decoded); err != nil {
+		t.Fatalf("Error decoding JSON: %v", err)
+	}
+	if !reflect.DeepEqual(report, decoded) {
+		t.Errorf("JSON round trip was incorrect, got: %+v, want: %+v.", decoded, report)
+	}
+
+	// The thirds grid is selectable, although it is the zero CompositionGrid
+	thirds := CompositionGrid{Kind: GridThirds}
+	if report, err := DetectBlur(sharp, BlurOptions{Grid: &thirds}); err != nil || len(report.Regions) != len(thirds.Boxes(rect)) {
+		t.Errorf("Thirds regions was incorrect, got: %v (%v), want: %v.", len(report.Regions), err, len(thirds.Boxes(rect)))
+	}
+
+	for _, size := range []image.Rectangle{image.Rect(0, 0, 2, 2), image.Rect(0, 0, 40, 2), image.Rect(0, 0, 1, 1), {}} {
+		if _, err := DetectBlur(testTexture(size, size), BlurOptions{}); err == nil || err.Error() != ErrorBlurSize {
+			t.Errorf("DetectBlur error of %v was incorrect, got: %v, want: %v.", size, err, ErrorBlurSize)
+		}
+		if _, err := NewBlurMap(testTexture(size, size), BlurOptions{}); err == nil || err.Error() != ErrorBlurSize {
+			t.Errorf("NewBlurMap error of %v was incorrect, got: %v, want: %v.", size, err, ErrorBlurSize)
+		}
+		if _, err := MeasureSharpnessGrid(testTexture(size, size), NewNxMGrid(1, 1)); err == nil || err.Error() != ErrorBlurSize {
+			t.Errorf("MeasureSharpnessGrid error of %v was incorrect, got: %v, want: %v.", size, err, ErrorBlurSize)
+		}
+	}
+	// 1000x4 is downscaled to 500x2
+	if _, err := DetectBlur(testTexture(image.Rect(0, 0, 1000, 4), image.Rect(0, 0, 1000, 4)), BlurOptions{}); err == nil || err.Error() != ErrorBlurSize {
+		t.Errorf("DetectBlur error of a downscaled image was incorrect, got: %v, want: %v.", err, ErrorBlurSize)
+	}
+
+	if _, err := DetectBlur(sharp, BlurOptions{Measure: 3}); err == nil || err.Error() != ErrorFocusMeasure {
+		t.Errorf("Error was incorrect, got: %v, want: %v.", err, ErrorFocusMeasure)
+	}
+	if _, err := ParseFocusMeasure("sharpness"); err == nil || err.Error() != ErrorFocusMeasure {
+		t.Errorf("Error was incorrect, got: %v, want: %v.", err, ErrorFocusMeasure)
+	}
+}
+
+func TestCalibrateBlur(t *testing.T) {
+	rect := image.Rect(0, 0, 60, 40)
+	sharp := []image.Image{testTexture(rect, rect), testBoxBlur(testTexture(rect, rect), 1)}
+	blurry := []image.Image{testBoxBlur(testTexture(rect, rect), 4), testTexture(rect, image.Rectangle{})}
+	threshold, err := CalibrateBlur(sharp, blurry, BlurOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, images := range [][]image.Image{sharp, blurry} {
+		for _, m := range images {
+			if report, _ := DetectBlur(m, BlurOptions{Threshold: threshold}); report.Blurry != (i == 1) {
+				t.Errorf("Calibrated threshold %v was incorrect, got: blurry %v score %v, want: blurry %v.", threshold, report.Blurry, report.Score, i == 1)
+			}
+		}
+	}
+	if _, err := CalibrateBlur(sharp, nil, BlurOptions{}); err == nil || err.Error() != ErrorBlurCalibration {
+		t.Errorf("Error was incorrect, got: %v, want: %v.", err, ErrorBlurCalibration)
+	}
+}
+
+func TestNewBlurMap(t *testing.T) {
+	rect := image.Rect(0, 0, 200, 100)
+	bm, err := NewBlurMap(testTexture(rect, image.Rect(0, 0, 100, 100)), BlurOptions{Size: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bm) != 200 || len(bm[0]) != 100 {
+		t.Fatalf("Size was incorrect, got: %vx%v, want: %vx%v.", len(bm), len(bm[0]), 200, 100)
+	}
+	for x := range bm {
+		for y, v := range bm[x] {
+			if v < 0 || v > 1 {
+				t.Fatalf("Blur at %v,%v was incorrect, got: %v, want: in [0, 1].", x, y, v)
+			}
+		}
+	}
+	if sharp, flat := bm[50][50], bm[150][50]; sharp > 0.1 || flat != 1 {
+		t.Errorf("Blur was incorrect, got: %v and %v, want: sharp texture below 0.1 and flat 1.", sharp, flat)
+	}
+}
+
+// TestDefaultBlurThresholds - A photo measured at 500 pixels is sharp when blurred with a
+// gaussian of sigma 0.5 pixel and blurry with a sigma of 1 pixel, with every measure
+func TestDefaultBlurThresholds(t *testing.T) {
+	for _, file := range []string{"hash/tests/test3.jpg"} {
+		f, err := os.Open(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := jpeg.Decode(f)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		m = blurDownscale(m, defaultBlurSize)
+		for _, tt := range []struct {
+			sigma  float64
+			blurry bool
+		}{{0.5, false}, {1, true}} {
+			blurred := testGaussianBlur(m, tt.sigma)
+			for _, fm := range []FocusMeasure{VarianceOfLaplacian, Tenengrad, ModifiedLaplacian} {
+				report, err := DetectBlur(blurred, BlurOptions{Measure: fm})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if report.Blurry != tt.blurry {
+					t.Errorf("%s at sigma %v %v was incorrect, got: blurry %v score %v, want: blurry %v with threshold %v.", file, tt.sigma, fm, report.Blurry, report.Score, tt.blurry, report.Threshold)
+				}
+			}
+		}
+	}
+}
+
+// TestLumaPlane - YCbCr images have the same Rec. 709 luma as their RGB conversion
+func TestLumaPlane(t *testing.T) {
+	m := image.NewYCbCr(image.Rect(0, 0, 4, 1), image.YCbCrSubsampleRatio444)
+	for i := range m.Y {
+		m.Y[i], m.Cb[i], m.Cr[i] = uint8(40+50*i), uint8(200-40*i), uint8(60+40*i)
+	}
+	rgba := image.NewRGBA(m.Bounds())
+	for x := 0; x < 4; x++ {
+		rgba.Set(x, 0, m.At(x, 0))
+	}
+	// BT.601 luma (the Y plane) differs by up to 17 levels, rounding by less than 1
+	got, want := lumaPlane(m), lumaPlane(rgba)
+	for i := range want {
+		if math.Abs(got[i]-want[i]) > 1 {
+			t.Errorf("YCbCr luma was incorrect, got: %v, want: %v.", got, want)
+			break
+		}
+	}
+}
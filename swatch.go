@@ -0,0 +1,226 @@
+package imagecolor
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+	"math"
+
+	"github.com/lucasb-eyer/go-colorful"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// SwatchLayout - Layout of a palette swatch
+type SwatchLayout uint8
+
+// Swatch Layouts
+const (
+	// SwatchBar is a horizontal bar with segments proportional to each color's weight.
+	SwatchBar SwatchLayout = iota
+	// SwatchGrid is a grid of equally sized swatches ordered by weight.
+	SwatchGrid
+	// SwatchLabels is a column of swatches labeled with color name and percentage.
+	SwatchLabels
+)
+
+// Swatch Defaults
+const (
+	defaultSwatchWidth     = 400
+	defaultSwatchBarHeight = 40
+	defaultSwatchRowHeight = 24
+	swatchLabelPadding     = 6
+)
+
+// SwatchOptions - Options for rendering a palette swatch.
+// Zero values are replaced with defaults.
+type SwatchOptions struct {
+	Layout  SwatchLayout
+	Width   int
+	Height  int
+	Columns int // SwatchGrid only, defaults to ceil(sqrt(n))
+}
+
+// swatchColor - Color, weight and label of a swatch
+type swatchColor struct {
+	Color colorful.Color
+	W     float64
+	Label string
+}
+
+// swatchCell - A single rectangle of a swatch layout
+type swatchCell struct {
+	Rect  image.Rectangle
+	Color swatchColor
+}
+
+// Swatch - Render the prominent colors as an image
+func (pc ProminentColors) Swatch(opts SwatchOptions) image.Image {
+	return drawSwatch(pc.swatchColors(), opts)
+}
+
+// WriteSVG - Write the prominent colors as an SVG document using the same layouts as Swatch
+func (pc ProminentColors) WriteSVG(w io.Writer, opts SwatchOptions) error {
+	return writeSwatchSVG(w, pc.swatchColors(), opts)
+}
+
+// Swatch - Render the dominant colors as an image, labeled with their hex values
+func (dc DominantColors) Swatch(opts SwatchOptions) image.Image {
+	return drawSwatch(dc.swatchColors(), opts)
+}
+
+// WriteSVG - Write the dominant colors as an SVG document using the same layouts as Swatch
+func (dc DominantColors) WriteSVG(w io.Writer, opts SwatchOptions) error {
+	return writeSwatchSVG(w, dc.swatchColors(), opts)
+}
+
+func (pc ProminentColors) swatchColors() []swatchColor {
+	colors := make([]swatchColor, len(pc.Colors))
+	for i, p := range pc.Colors {
+		colors[i] = swatchColor{Color: p.Color.Colorful(), W: p.W, Label: swatchLabel(p.Color.String(), p.W)}
+	}
+	return colors
+}
+
+func (dc DominantColors) swatchColors() []swatchColor {
+	colors := make([]swatchColor, len(dc))
+	for i, d := range dc {
+		c := d.Color.Colorful().Clamped()
+		colors[i] = swatchColor{Color: c, W: d.W, Label: swatchLabel(c.Hex(), d.W)}
+	}
+	return colors
+}
+
+// drawSwatch - Render colors as an image
+func drawSwatch(colors []swatchColor, opts SwatchOptions) image.Image {
+	opts = opts.withDefaults(len(colors))
+	img := image.NewRGBA(image.Rect(0, 0, opts.Width, opts.Height))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+
+	for _, cell := range swatchCells(colors, opts) {
+		draw.Draw(img, cell.Rect, image.NewUniform(cell.Color.Color), image.Point{}, draw.Src)
+		if opts.Layout == SwatchLabels {
+			d := font.Drawer{
+				Dst:  img,
+				Src:  image.NewUniform(swatchTextColor(cell.Color.Color)),
+				Face: basicfont.Face7x13,
+				Dot: fixed.P(cell.Rect.Min.X+swatchLabelPadding,
+					cell.Rect.Min.Y+(cell.Rect.Dy()+basicfont.Face7x13.Ascent-basicfont.Face7x13.Descent)/2),
+			}
+			d.DrawString(cell.Color.Label)
+		}
+	}
+	return img
+}
+
+// writeSwatchSVG - Write colors as an SVG document
+func writeSwatchSVG(w io.Writer, colors []swatchColor, opts SwatchOptions) error {
+	opts = opts.withDefaults(len(colors))
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n",
+		opts.Width, opts.Height, opts.Width, opts.Height)
+	fmt.Fprintf(bw, `<rect width="%d" height="%d" fill="#ffffff"/>`+"\n", opts.Width, opts.Height)
+	for _, cell := range swatchCells(colors, opts) {
+		r := cell.Rect
+		fmt.Fprintf(bw, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s"/>`+"\n",
+			r.Min.X, r.Min.Y, r.Dx(), r.Dy(), cell.Color.Color.Hex())
+		if opts.Layout == SwatchLabels {
+			tc := colorfulFromColor(swatchTextColor(cell.Color.Color))
+			fmt.Fprintf(bw, `<text x="%d" y="%d" dominant-baseline="middle" font-family="monospace" font-size="13" fill="%s">%s</text>`+"\n",
+				r.Min.X+swatchLabelPadding, r.Min.Y+r.Dy()/2, tc.Hex(), cell.Color.Label)
+		}
+	}
+	fmt.Fprint(bw, "</svg>\n")
+	return bw.Flush()
+}
+
+// withDefaults - Replace zero values of SwatchOptions with defaults
+func (opts SwatchOptions) withDefaults(n int) SwatchOptions {
+	if opts.Width <= 0 {
+		opts.Width = defaultSwatchWidth
+	}
+	if opts.Columns <= 0 {
+		opts.Columns = int(math.Ceil(math.Sqrt(float64(n))))
+		if opts.Columns == 0 {
+			opts.Columns = 1
+		}
+	}
+	if opts.Height <= 0 {
+		switch opts.Layout {
+		case SwatchGrid:
+			rows := (n + opts.Columns - 1) / opts.Columns
+			opts.Height = rows * (opts.Width / opts.Columns)
+		case SwatchLabels:
+			opts.Height = n * defaultSwatchRowHeight
+		default:
+			opts.Height = defaultSwatchBarHeight
+		}
+		if opts.Height <= 0 {
+			opts.Height = defaultSwatchBarHeight
+		}
+	}
+	return opts
+}
+
+// swatchCells - Compute the rectangles of a swatch layout
+func swatchCells(colors []swatchColor, opts SwatchOptions) (cells []swatchCell) {
+	n := len(colors)
+	if n == 0 {
+		return nil
+	}
+	switch opts.Layout {
+	case SwatchGrid:
+		rows := (n + opts.Columns - 1) / opts.Columns
+		for i, p := range colors {
+			col, row := i%opts.Columns, i/opts.Columns
+			cells = append(cells, swatchCell{Color: p, Rect: image.Rect(
+				col*opts.Width/opts.Columns, row*opts.Height/rows,
+				(col+1)*opts.Width/opts.Columns, (row+1)*opts.Height/rows)})
+		}
+	case SwatchLabels:
+		for i, p := range colors {
+			cells = append(cells, swatchCell{Color: p, Rect: image.Rect(
+				0, i*opts.Height/n, opts.Width, (i+1)*opts.Height/n)})
+		}
+	default:
+		var total, sum float64
+		for _, p := range colors {
+			total += p.W
+		}
+		x0 := 0
+		for _, p := range colors {
+			sum += p.W
+			x1 := opts.Width
+			if total > 0 {
+				x1 = int(math.Round(sum / total * float64(opts.Width)))
+			}
+			if x1 > x0 {
+				cells = append(cells, swatchCell{Color: p, Rect: image.Rect(x0, 0, x1, opts.Height)})
+			}
+			x0 = x1
+		}
+	}
+	return cells
+}
+
+// swatchLabel - Label of a swatch, ex: "Red 25.00%"
+func swatchLabel(name string, w float64) string {
+	return fmt.Sprintf("%s %.2f%%", name, w*100)
+}
+
+// swatchTextColor - Black or White depending on which is more legible on the color
+func swatchTextColor(c colorful.Color) color.Color {
+	_, _, l := c.Hsl()
+	if l > 0.55 {
+		return color.Black
+	}
+	return color.White
+}
+
+func colorfulFromColor(c color.Color) colorful.Color {
+	return newColorful(c.RGBA())
+}
@@ -0,0 +1,139 @@
+package imagecolor
+
+import (
+	"bytes"
+	"encoding/xml"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+// testSVG - Rects and texts of an SVG swatch
+type testSVG struct {
+	Width  int `xml:"width,attr"`
+	Height int `xml:"height,attr"`
+	Rects  []struct {
+		X      int    `xml:"x,attr"`
+		Width  int    `xml:"width,attr"`
+		Height int    `xml:"height,attr"`
+		Fill   string `xml:"fill,attr"`
+	} `xml:"rect"`
+	Texts []string `xml:"text"`
+}
+
+func TestSwatch(t *testing.T) {
+	pc := ProminentColors{Colors: []ProminentColor{{Color: materialRed, W: 0.5}, {Color: materialBlue, W: 0.3}, {Color: materialBlack, W: 0.2}}}
+	rgb := func(mc MaterialColor) color.RGBA {
+		r, g, b := mc.Colorful().RGB255()
+		return color.RGBA{r, g, b, 0xff}
+	}
+	tests := []struct {
+		layout SwatchLayout
+		size   image.Point
+		pixels map[image.Point]color.RGBA
+	}{
+		{SwatchBar, image.Pt(400, 40), map[image.Point]color.RGBA{{10, 20}: rgb(materialRed), {199, 20}: rgb(materialRed), {200, 20}: rgb(materialBlue), {330, 20}: rgb(materialBlack)}},
+		{SwatchGrid, image.Pt(400, 400), map[image.Point]color.RGBA{{10, 10}: rgb(materialRed), {210, 10}: rgb(materialBlue), {10, 210}: rgb(materialBlack), {210, 210}: {0xff, 0xff, 0xff, 0xff}}},
+		{SwatchLabels, image.Pt(400, 72), map[image.Point]color.RGBA{{398, 2}: rgb(materialRed), {398, 26}: rgb(materialBlue), {398, 50}: rgb(materialBlack)}},
+	}
+	for _, tt := range tests {
+		m := pc.Swatch(SwatchOptions{Layout: tt.layout}).(*image.RGBA)
+		if m.Bounds().Size() != tt.size {
+			t.Errorf("Swatch %v size was incorrect, got: %v, want: %v.", tt.layout, m.Bounds().Size(), tt.size)
+		}
+		for p, want := range tt.pixels {
+			if got := m.RGBAAt(p.X, p.Y); got != want {
+				t.Errorf("Swatch %v pixel %v was incorrect, got: %v, want: %v.", tt.layout, p, got, want)
+			}
+		}
+
+		var buf bytes.Buffer
+		if err := pc.WriteSVG(&buf, SwatchOptions{Layout: tt.layout}); err != nil {
+			t.Fatal(err)
+		}
+		var svg testSVG
+		if err := xml.Unmarshal(buf.Bytes(), &svg); err != nil {
+			t.Fatalf("SVG %v was invalid: %v", tt.layout, err)
+		}
+		if svg.Width != tt.size.X || svg.Height != tt.size.Y || len(svg.Rects) != 4 {
+			t.Fatalf("SVG %v was incorrect, got: %vx%v with %v rects, want: %v with %v rects.", tt.layout, svg.Width, svg.Height, len(svg.Rects), tt.size, 4)
+		}
+		for i, c := range pc.Colors {
+			if fill := svg.Rects[i+1].Fill; fill != c.Color.Hex() {
+				t.Errorf("SVG %v fill %d was incorrect, got: %v, want: %v.", tt.layout, i, fill, c.Color.Hex())
+			}
+		}
+		if tt.layout == SwatchLabels {
+			if len(svg.Texts) != 3 || svg.Texts[0] != "Red 50.00%" {
+				t.Errorf("SVG labels was incorrect, got: %v, want: %v.", svg.Texts, "Red 50.00% first")
+			}
+			// The label is drawn in black on red
+			text := false
+			for x := 0; x < 100; x++ {
+				text = text || m.RGBAAt(x, 12) == color.RGBA{0, 0, 0, 0xff}
+			}
+			if !text {
+				t.Errorf("Swatch label was not drawn.")
+			}
+		} else if len(svg.Texts) != 0 {
+			t.Errorf("SVG %v labels was incorrect, got: %v, want: none.", tt.layout, svg.Texts)
+		}
+	}
+
+	// Bar segments are proportional to the weights
+	var buf bytes.Buffer
+	if err := pc.WriteSVG(&buf, SwatchOptions{Width: 100, Height: 10}); err != nil {
+		t.Fatal(err)
+	}
+	var svg testSVG
+	if err := xml.Unmarshal(buf.Bytes(), &svg); err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []int{50, 30, 20} {
+		if w := svg.Rects[i+1].Width; w != want {
+			t.Errorf("Bar segment %d was incorrect, got: %v, want: %v.", i, w, want)
+		}
+	}
+
+	if m := (ProminentColors{}).Swatch(SwatchOptions{}); m.Bounds() != image.Rect(0, 0, 400, 40) || m.At(0, 0) != (color.RGBA{0xff, 0xff, 0xff, 0xff}) {
+		t.Errorf("Empty swatch was incorrect, got: %v %v, want: white %v.", m.Bounds(), m.At(0, 0), image.Rect(0, 0, 400, 40))
+	}
+}
+
+func TestDominantColorsSwatch(t *testing.T) {
+	ic := testHalves(20, 10)
+	dc := ic.ClassMap().DominantColors(*ic, LinearAveraging, 0)
+	if len(dc) != 2 || dc[0].Class != materialRed || dc[1].Class != materialBlue || dc[0].W != 0.5 {
+		t.Fatalf("DominantColors was incorrect, got: %+v, want: red and blue halves.", dc)
+	}
+	if hex := dc[0].Color.Colorful().Clamped().Hex(); hex != "#f44336" {
+		t.Errorf("Dominant color was incorrect, got: %v, want: %v.", hex, "#f44336")
+	}
+	if len(ic.ClassMap().DominantColors(*ic, GammaAveraging, 0.5)) != 0 {
+		t.Errorf("DominantColors limit was incorrect, want: no colors above %v.", 0.5)
+	}
+
+	m := dc.Swatch(SwatchOptions{}).(*image.RGBA)
+	if got, want := m.RGBAAt(100, 20), (color.RGBA{0xf4, 0x43, 0x36, 0xff}); got != want {
+		t.Errorf("Swatch pixel was incorrect, got: %v, want: %v.", got, want)
+	}
+	if got, want := m.RGBAAt(300, 20), (color.RGBA{0x21, 0x96, 0xf3, 0xff}); got != want {
+		t.Errorf("Swatch pixel was incorrect, got: %v, want: %v.", got, want)
+	}
+
+	var buf bytes.Buffer
+	if err := dc.WriteSVG(&buf, SwatchOptions{Layout: SwatchLabels}); err != nil {
+		t.Fatal(err)
+	}
+	var svg testSVG
+	if err := xml.Unmarshal(buf.Bytes(), &svg); err != nil {
+		t.Fatal(err)
+	}
+	if len(svg.Rects) != 3 || svg.Rects[1].Fill != "#f44336" || svg.Rects[2].Fill != "#2196f3" {
+		t.Errorf("SVG rects was incorrect, got: %+v, want: %v.", svg.Rects, "#f44336 and #2196f3")
+	}
+	if strings.Join(svg.Texts, ",") != "#f44336 50.00%,#2196f3 50.00%" {
+		t.Errorf("SVG labels was incorrect, got: %v, want: %v.", svg.Texts, "#f44336 50.00%,#2196f3 50.00%")
+	}
+}
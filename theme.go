@@ -0,0 +1,211 @@
+package imagecolor
+
+import (
+	"image"
+	"math"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+// Theme Defaults.
+// Chroma values are in OKLCh units and approximate the Material 3
+// "tonal spot" scheme (HCT chroma 36, 16, 24, 4 and 8).
+const (
+	primaryChroma        = 0.13
+	secondaryChroma      = 0.045
+	tertiaryChroma       = 0.07
+	neutralChroma        = 0.012
+	neutralVariantChroma = 0.024
+	tertiaryHueShift     = 60.0
+	errorHue             = 29.0 // OKLCh hue of Material red
+	errorChroma          = 0.19
+	seedMinChroma        = 0.03
+	toneIterations       = 24
+)
+
+// defaultSeed - Seed used for images without chromatic colors (Google Blue, #4285f4)
+var defaultSeed = colorful.Color{R: 0x42 / 255.0, G: 0x85 / 255.0, B: 0xf4 / 255.0}
+
+// Tones - Standard tones of a Material 3 TonalPalette
+var Tones = []float64{0, 10, 20, 30, 40, 50, 60, 70, 80, 90, 95, 99, 100}
+
+// TonalPalette - Colors of a single OKLCh hue and chroma at every tone.
+// Tone is the CIE L* lightness in the range [0, 100], as in Material 3.
+type TonalPalette struct {
+	Hue    float64
+	Chroma float64
+}
+
+// Tone - Color of the TonalPalette at tone [0, 100].
+// Chroma is reduced when needed to stay in the sRGB gamut.
+func (tp TonalPalette) Tone(tone float64) colorful.Color {
+	if tone <= 0 {
+		return colorful.Color{}
+	}
+	if tone >= 100 {
+		return colorful.Color{R: 1, G: 1, B: 1}
+	}
+	if c, ok := toneColor(tp.Hue, tp.Chroma, tone); ok {
+		return c
+	}
+	lo, hi := 0.0, tp.Chroma
+	best, _ := toneColor(tp.Hue, 0, tone)
+	for i := 0; i < toneIterations; i++ {
+		mid := (lo + hi) / 2
+		if c, ok := toneColor(tp.Hue, mid, tone); ok {
+			best, lo = c, mid
+		} else {
+			hi = mid
+		}
+	}
+	return best
+}
+
+// Tones - Colors of the TonalPalette at the standard Tones
+func (tp TonalPalette) Tones() map[float64]colorful.Color {
+	res := make(map[float64]colorful.Color, len(Tones))
+	for _, t := range Tones {
+		res[t] = tp.Tone(t)
+	}
+	return res
+}
+
+// toneColor - Color of hue and chroma whose CIE L* is tone, and whether it is in the sRGB gamut
+func toneColor(hue, chroma, tone float64) (colorful.Color, bool) {
+	h := hue * math.Pi / 180
+	a, b := chroma*math.Cos(h), chroma*math.Sin(h)
+	var r, g, bl float64
+	lo, hi := 0.0, 1.0
+	for i := 0; i < toneIterations; i++ {
+		l := (lo + hi) / 2
+		r, g, bl = okLabToLinear(l, a, b)
+		if lstar(0.2126*r+0.7152*g+0.0722*bl) < tone {
+			lo = l
+		} else {
+			hi = l
+		}
+	}
+	const eps = 1e-4
+	ok := r >= -eps && g >= -eps && bl >= -eps && r <= 1+eps && g <= 1+eps && bl <= 1+eps
+	return colorful.LinearRgb(clamp01(r), clamp01(g), clamp01(bl)), ok
+}
+
+// lstar - CIE L* lightness of the relative luminance y
+func lstar(y float64) float64 {
+	if y > 216.0/24389 {
+		return 116*math.Cbrt(y) - 16
+	}
+	return y * 24389 / 27
+}
+
+// Scheme - Material 3 color roles
+type Scheme struct {
+	Primary, OnPrimary, PrimaryContainer, OnPrimaryContainer         colorful.Color
+	Secondary, OnSecondary, SecondaryContainer, OnSecondaryContainer colorful.Color
+	Tertiary, OnTertiary, TertiaryContainer, OnTertiaryContainer     colorful.Color
+	Error, OnError, ErrorContainer, OnErrorContainer                 colorful.Color
+	Background, OnBackground                                         colorful.Color
+	Surface, OnSurface, SurfaceVariant, OnSurfaceVariant             colorful.Color
+	Outline, OutlineVariant                                          colorful.Color
+	InverseSurface, InverseOnSurface, InversePrimary                 colorful.Color
+	Shadow, Scrim                                                    colorful.Color
+}
+
+// Theme - Material 3 tonal palettes and light and dark schemes derived from a seed color
+type Theme struct {
+	Seed                           colorful.Color
+	Primary, Secondary, Tertiary   TonalPalette
+	Neutral, NeutralVariant, Error TonalPalette
+	Light, Dark                    Scheme
+}
+
+// NewTheme - Theme from a seed color
+func NewTheme(seed colorful.Color) Theme {
+	_, chroma, hue := OkLch(seed)
+	t := Theme{
+		Seed:           seed,
+		Primary:        TonalPalette{Hue: hue, Chroma: math.Max(chroma, primaryChroma)},
+		Secondary:      TonalPalette{Hue: hue, Chroma: secondaryChroma},
+		Tertiary:       TonalPalette{Hue: math.Mod(hue+tertiaryHueShift, 360), Chroma: tertiaryChroma},
+		Neutral:        TonalPalette{Hue: hue, Chroma: neutralChroma},
+		NeutralVariant: TonalPalette{Hue: hue, Chroma: neutralVariantChroma},
+		Error:          TonalPalette{Hue: errorHue, Chroma: errorChroma},
+	}
+	t.Light = t.scheme(false)
+	t.Dark = t.scheme(true)
+	return t
+}
+
+// ThemeFromImage - Theme from the SeedColor of an image
+func ThemeFromImage(m image.Image) Theme {
+	return NewTheme(SeedColor(m))
+}
+
+// SeedColor - Seed color of an image: the mean color of the MaterialColor class that
+// best combines coverage and chroma. Returns Google Blue for images without chromatic colors.
+func SeedColor(m image.Image) colorful.Color {
+	ic := GetImageColors(m)
+	cm := ic.ClassMap()
+	centroids := cm.Centroids(*ic, LinearAveraging)
+	weights := cm.Weights()
+
+	seed, bestScore := defaultSeed, 0.0
+	for mc := materialRed; mc <= materialBlack; mc++ {
+		centroid, ok := centroids[mc]
+		if !ok {
+			continue
+		}
+		c := centroid.Colorful().Clamped()
+		_, chroma, _ := OkLch(c)
+		if chroma < seedMinChroma {
+			continue
+		}
+		if score := math.Sqrt(weights[mc]) * chroma; score > bestScore {
+			seed, bestScore = c, score
+		}
+	}
+	return seed
+}
+
+// scheme - Light or dark Scheme using the Material 3 baseline tones
+func (t Theme) scheme(dark bool) Scheme {
+	// tone picks the light or the dark tone of a role
+	tone := func(tp TonalPalette, light, darkTone float64) colorful.Color {
+		if dark {
+			return tp.Tone(darkTone)
+		}
+		return tp.Tone(light)
+	}
+	p, s, tt, e, n, nv := t.Primary, t.Secondary, t.Tertiary, t.Error, t.Neutral, t.NeutralVariant
+	return Scheme{
+		Primary:              tone(p, 40, 80),
+		OnPrimary:            tone(p, 100, 20),
+		PrimaryContainer:     tone(p, 90, 30),
+		OnPrimaryContainer:   tone(p, 10, 90),
+		Secondary:            tone(s, 40, 80),
+		OnSecondary:          tone(s, 100, 20),
+		SecondaryContainer:   tone(s, 90, 30),
+		OnSecondaryContainer: tone(s, 10, 90),
+		Tertiary:             tone(tt, 40, 80),
+		OnTertiary:           tone(tt, 100, 20),
+		TertiaryContainer:    tone(tt, 90, 30),
+		OnTertiaryContainer:  tone(tt, 10, 90),
+		Error:                tone(e, 40, 80),
+		OnError:              tone(e, 100, 20),
+		ErrorContainer:       tone(e, 90, 30),
+		OnErrorContainer:     tone(e, 10, 90),
+		Background:           tone(n, 99, 10),
+		OnBackground:         tone(n, 10, 90),
+		Surface:              tone(n, 99, 10),
+		OnSurface:            tone(n, 10, 90),
+		SurfaceVariant:       tone(nv, 90, 30),
+		OnSurfaceVariant:     tone(nv, 30, 80),
+		Outline:              tone(nv, 50, 60),
+		OutlineVariant:       tone(nv, 80, 30),
+		InverseSurface:       tone(n, 20, 90),
+		InverseOnSurface:     tone(n, 95, 20),
+		InversePrimary:       tone(p, 80, 40),
+		Shadow:               n.Tone(0),
+		Scrim:                n.Tone(0),
+	}
+}
@@ -0,0 +1,92 @@
+package imagecolor
+
+import (
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+// testLstar - CIE L* of a color
+func testLstar(c colorful.Color) float64 {
+	r, g, b := c.LinearRgb()
+	return lstar(lumaR*r + lumaG*g + lumaB*b)
+}
+
+func TestTonalPalette(t *testing.T) {
+	palettes := []TonalPalette{
+		{Hue: 25, Chroma: 0.13},
+		{Hue: 140, Chroma: 0.4}, // out of gamut at most tones
+		{Hue: 265, Chroma: 0.045},
+		{Hue: 0, Chroma: 0},
+	}
+	for _, tp := range palettes {
+		prev := -1.0
+		for _, tone := range Tones {
+			c := tp.Tone(tone)
+			if !c.IsValid() {
+				t.Errorf("Tone %v of %+v was incorrect, got: %v, want: in the sRGB gamut.", tone, tp, c)
+			}
+			l := testLstar(c)
+			if math.Abs(l-tone) > 0.5 {
+				t.Errorf("L* of tone %v of %+v was incorrect, got: %v, want: %v.", tone, tp, l, tone)
+			}
+			if l <= prev {
+				t.Errorf("L* of tone %v of %+v was incorrect, got: %v, want: more than %v.", tone, tp, l, prev)
+			}
+			prev = l
+		}
+		if tones := tp.Tones(); len(tones) != len(Tones) || tones[40] != tp.Tone(40) {
+			t.Errorf("Tones of %+v was incorrect, got: %v colors, want: %v.", tp, len(tones), len(Tones))
+		}
+	}
+
+	// Gamut mapping keeps the hue and reduces the chroma
+	c := palettes[1].Tone(50)
+	_, chroma, h := OkLch(c)
+	if chroma >= palettes[1].Chroma || math.Abs(h-palettes[1].Hue) > 1 {
+		t.Errorf("Gamut mapped tone was incorrect, got: chroma %v hue %v, want: less chroma and hue %v.", chroma, h, palettes[1].Hue)
+	}
+}
+
+func TestTheme(t *testing.T) {
+	seed, _ := colorful.Hex("#2196f3")
+	theme := NewTheme(seed)
+	_, _, hue := OkLch(seed)
+	if theme.Primary.Hue != hue || theme.Tertiary.Hue != math.Mod(hue+tertiaryHueShift, 360) {
+		t.Errorf("Hues was incorrect, got: %v %v, want: %v %v.", theme.Primary.Hue, theme.Tertiary.Hue, hue, math.Mod(hue+tertiaryHueShift, 360))
+	}
+	tests := []struct {
+		name        string
+		color, on   colorful.Color
+		light, dark float64
+	}{
+		{"light primary", theme.Light.Primary, theme.Light.OnPrimary, 40, 100},
+		{"dark primary", theme.Dark.Primary, theme.Dark.OnPrimary, 80, 20},
+		{"light surface", theme.Light.Surface, theme.Light.OnSurface, 99, 10},
+		{"dark surface", theme.Dark.Surface, theme.Dark.OnSurface, 10, 90},
+	}
+	for _, tt := range tests {
+		if l, on := testLstar(tt.color), testLstar(tt.on); math.Abs(l-tt.light) > 0.5 || math.Abs(on-tt.dark) > 0.5 {
+			t.Errorf("%s tones was incorrect, got: %v and %v, want: %v and %v.", tt.name, l, on, tt.light, tt.dark)
+		}
+	}
+
+	// The seed is the most colorful large class, gray images use the default seed
+	m := testFill(40, 40, color.RGBA{0x80, 0x80, 0x80, 0xff})
+	if s := SeedColor(m); s != defaultSeed {
+		t.Errorf("Gray seed was incorrect, got: %v, want: %v.", s.Hex(), defaultSeed.Hex())
+	}
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 40; x++ {
+			m.SetRGBA(x, y, testRed)
+		}
+	}
+	if s := SeedColor(m).Clamped().Hex(); s != "#f44336" {
+		t.Errorf("Seed was incorrect, got: %v, want: %v.", s, "#f44336")
+	}
+	if th := ThemeFromImage(m); th.Seed.Clamped().Hex() != "#f44336" {
+		t.Errorf("ThemeFromImage seed was incorrect, got: %v, want: %v.", th.Seed.Hex(), "#f44336")
+	}
+}
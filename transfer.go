@@ -0,0 +1,226 @@
+package imagecolor
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"sort"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+// LabStats - Mean and Standard deviation of an image in the CIE Lab colorspace
+type LabStats struct {
+	Mean [3]float64
+	Std  [3]float64
+}
+
+// LabStatistics - Mean and Standard deviation of the L, a and b channels of an image
+func LabStatistics(m image.Image) LabStats {
+	var stats LabStats
+	var sum, sumSq [3]float64
+	bounds := m.Bounds()
+	n := float64(bounds.Dx() * bounds.Dy())
+	if n == 0 {
+		return stats
+	}
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c, _ := straightColor(m.At(x, y))
+			l, a, b := c.Lab()
+			for i, v := range [3]float64{l, a, b} {
+				sum[i] += v
+				sumSq[i] += v * v
+			}
+		}
+	}
+	for i := range sum {
+		stats.Mean[i] = sum[i] / n
+		stats.Std[i] = math.Sqrt(math.Max(sumSq[i]/n-stats.Mean[i]*stats.Mean[i], 0))
+	}
+	return stats
+}
+
+// TransferReinhard - Transfer the colors of target to src by matching the
+// mean and standard deviation of each Lab channel (Reinhard et al. 2001).
+func TransferReinhard(src, target image.Image) *image.NRGBA {
+	s, t := LabStatistics(src), LabStatistics(target)
+	var scale [3]float64
+	for i := range scale {
+		scale[i] = 1
+		if s.Std[i] > 0 {
+			scale[i] = t.Std[i] / s.Std[i]
+		}
+	}
+	return mapColors(src, func(c colorful.Color) colorful.Color {
+		l, a, b := c.Lab()
+		lab := [3]float64{l, a, b}
+		for i := range lab {
+			lab[i] = (lab[i]-s.Mean[i])*scale[i] + t.Mean[i]
+		}
+		return colorful.Lab(lab[0], lab[1], lab[2])
+	})
+}
+
+// TransferHistogram - Transfer the colors of target to src by matching
+// the histogram of each RGB channel.
+func TransferHistogram(src, target image.Image) *image.NRGBA {
+	sh, th := channelHistograms(src), channelHistograms(target)
+	var lut [3][256]uint8
+	for ch := 0; ch < 3; ch++ {
+		scdf, tcdf := cdf(sh[ch]), cdf(th[ch])
+		j := 0
+		for i := 0; i < 256; i++ {
+			for j < 255 && tcdf[j] < scdf[i] {
+				j++
+			}
+			lut[ch][i] = uint8(j)
+		}
+	}
+	return mapColors(src, func(c colorful.Color) colorful.Color {
+		r, g, b := c.RGB255()
+		return colorful.Color{
+			R: float64(lut[0][r]) / 255,
+			G: float64(lut[1][g]) / 255,
+			B: float64(lut[2][b]) / 255,
+		}
+	})
+}
+
+// RecolorToPalette - Map the dominant colors of src to the colors of palette.
+// Every MaterialColor class of src is assigned a palette color, dominant classes first,
+// and its pixels are shifted in Lab by the difference between the class centroid and
+// the assigned palette color. Pixels are classified by their straight (not alpha
+// premultiplied) color, and fully transparent pixels do not count towards dominance.
+func RecolorToPalette(src image.Image, palette color.Palette) *image.NRGBA {
+	if len(palette) == 0 {
+		return mapColors(src, func(c colorful.Color) colorful.Color { return c })
+	}
+	bounds := src.Bounds()
+	var ic ImageColors
+	ic.defineSize(bounds.Dx(), bounds.Dy())
+	alpha := make([][]uint8, bounds.Dx())
+	for x := range ic {
+		alpha[x] = make([]uint8, bounds.Dy())
+		for y := range ic[x] {
+			c, a := straightColor(src.At(x+bounds.Min.X, y+bounds.Min.Y))
+			ic[x][y], alpha[x][y] = NewColorHSL(c), a
+		}
+	}
+	cm := ic.ClassMap()
+	accs := make(map[MaterialColor]*colorAccumulator)
+	for x := range ic {
+		for y := range ic[x] {
+			if alpha[x][y] == 0 {
+				continue
+			}
+			mc := cm.At(x, y)
+			if accs[mc] == nil {
+				accs[mc] = &colorAccumulator{mode: GammaAveraging}
+			}
+			accs[mc].add(ic[x][y].Colorful(), 1)
+		}
+	}
+
+	classes := make([]MaterialColor, 0, len(accs))
+	for mc := range accs {
+		classes = append(classes, mc)
+	}
+	sort.Slice(classes, func(i, j int) bool {
+		if accs[classes[i]].n == accs[classes[j]].n {
+			return classes[i] < classes[j]
+		}
+		return accs[classes[i]].n > accs[classes[j]].n
+	})
+
+	targets := make([]colorful.Color, len(palette))
+	for i, p := range palette {
+		targets[i], _ = straightColor(p)
+	}
+	used := make([]bool, len(targets))
+	shift := make(map[MaterialColor][3]float64, len(classes))
+	for n, mc := range classes {
+		centroid := accs[mc].mean()
+		best, bestDist := -1, math.MaxFloat64
+		for i, t := range targets {
+			if used[i] && n < len(targets) {
+				continue
+			}
+			if d := centroid.DistanceLab(t); d < bestDist {
+				best, bestDist = i, d
+			}
+		}
+		used[best] = true
+		l1, a1, b1 := centroid.Lab()
+		l2, a2, b2 := targets[best].Lab()
+		shift[mc] = [3]float64{l2 - l1, a2 - a1, b2 - b1}
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			d := shift[cm.At(x, y)]
+			l, la, lb := ic[x][y].Colorful().Lab()
+			dst.SetNRGBA(x, y, nrgba(colorful.Lab(l+d[0], la+d[1], lb+d[2]), alpha[x][y]))
+		}
+	}
+	return dst
+}
+
+// mapColors - Apply fn to every pixel of src, preserving alpha
+func mapColors(src image.Image, fn func(colorful.Color) colorful.Color) *image.NRGBA {
+	bounds := src.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			c, a := straightColor(src.At(x+bounds.Min.X, y+bounds.Min.Y))
+			dst.SetNRGBA(x, y, nrgba(fn(c), a))
+		}
+	}
+	return dst
+}
+
+// channelHistograms - 8 bit histograms of the R, G and B channels
+func channelHistograms(m image.Image) (h [3][256]float64) {
+	bounds := m.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c, _ := straightColor(m.At(x, y))
+			r, g, b := c.RGB255()
+			h[0][r]++
+			h[1][g]++
+			h[2][b]++
+		}
+	}
+	return h
+}
+
+// cdf - Normalized cumulative distribution of a histogram
+func cdf(h [256]float64) (res [256]float64) {
+	var sum float64
+	for i, v := range h {
+		sum += v
+		res[i] = sum
+	}
+	if sum > 0 {
+		for i := range res {
+			res[i] /= sum
+		}
+	}
+	return res
+}
+
+// straightColor - Non alpha-premultiplied color and 8 bit alpha of c
+func straightColor(c color.Color) (colorful.Color, uint8) {
+	r, g, b, a := c.RGBA()
+	if a == 0 {
+		return colorful.Color{}, 0
+	}
+	fa := float64(a)
+	return colorful.Color{R: float64(r) / fa, G: float64(g) / fa, B: float64(b) / fa}, uint8(a >> 8)
+}
+
+func nrgba(c colorful.Color, a uint8) color.NRGBA {
+	r, g, b := c.Clamped().RGB255()
+	return color.NRGBA{R: r, G: g, B: b, A: a}
+}
@@ -0,0 +1,69 @@
+package imagecolor
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+// testTranslucent - Transparent image with a red square of alpha a, premultiplied or straight
+func testTranslucent(a uint8, premultiplied bool) image.Image {
+	rect, square := image.Rect(0, 0, 30, 30), image.Rect(10, 10, 20, 20)
+	c := color.NRGBA{testRed.R, testRed.G, testRed.B, a}
+	if premultiplied {
+		m := image.NewRGBA(rect)
+		for y := square.Min.Y; y < square.Max.Y; y++ {
+			for x := square.Min.X; x < square.Max.X; x++ {
+				m.Set(x, y, c)
+			}
+		}
+		return m
+	}
+	m := image.NewNRGBA(rect)
+	for y := square.Min.Y; y < square.Max.Y; y++ {
+		for x := square.Min.X; x < square.Max.X; x++ {
+			m.SetNRGBA(x, y, c)
+		}
+	}
+	return m
+}
+
+func TestRecolorToPalette(t *testing.T) {
+	gray := color.NRGBA{0x30, 0x30, 0x30, 0xff}
+	palette := color.Palette{gray, color.White}
+	want, _ := straightColor(gray)
+	for _, a := range []uint8{0xff, 0x80, 0x20} {
+		straight := RecolorToPalette(testTranslucent(a, false), palette)
+		premultiplied := RecolorToPalette(testTranslucent(a, true), palette)
+
+		// Only the red square counts, so it takes the nearest palette color, although
+		// most of the image is transparent
+		c := straight.NRGBAAt(15, 15)
+		got, _ := straightColor(color.NRGBA{c.R, c.G, c.B, 0xff})
+		if c.A != a || got.DistanceLab(want) > 0.02 {
+			t.Errorf("Recolored pixel with alpha %v was incorrect, got: %v, want: %v with alpha %v.", a, c, gray, a)
+		}
+		if c := straight.NRGBAAt(2, 2); c.A != 0 {
+			t.Errorf("Transparent pixel was incorrect, got: %v, want: alpha %v.", c, 0)
+		}
+
+		// Premultiplied and straight images only differ by the rounding of premultiplication
+		for y := 0; y < 30; y++ {
+			for x := 0; x < 30; x++ {
+				s, p := straight.NRGBAAt(x, y), premultiplied.NRGBAAt(x, y)
+				cs := colorful.Color{R: float64(s.R) / 255, G: float64(s.G) / 255, B: float64(s.B) / 255}
+				cp := colorful.Color{R: float64(p.R) / 255, G: float64(p.G) / 255, B: float64(p.B) / 255}
+				if s.A != p.A || cs.DistanceLab(cp) > 0.03 {
+					t.Fatalf("Premultiplied pixel %v,%v with alpha %v was incorrect, got: %v, want: %v.", x, y, a, p, s)
+				}
+			}
+		}
+	}
+
+	m := testTranslucent(0x80, false)
+	if res := RecolorToPalette(m, nil); res.NRGBAAt(15, 15) != m.(*image.NRGBA).NRGBAAt(15, 15) {
+		t.Errorf("Empty palette was incorrect, got: %v, want: %v.", res.NRGBAAt(15, 15), m.(*image.NRGBA).NRGBAAt(15, 15))
+	}
+}